@@ -2,8 +2,11 @@ package main
 
 import (
 	"api/src/config"
+	"api/src/logging"
 	"api/src/server"
 	"log"
+	"log/slog"
+	"os"
 
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -11,8 +14,16 @@ import (
 
 func main() {
 	config.Load()
-	if err := server.Start(); err != nil {
+
+	logger, err := logging.New(config.LogLevel, config.LogFormat, os.Stdout)
+	if err != nil {
 		log.Fatal(err)
 	}
+	slog.SetDefault(logger)
+
+	if err := server.Start(logger); err != nil {
+		logger.Error("server stopped", slog.Any("error", err))
+		os.Exit(1)
+	}
 
 }
@@ -0,0 +1,124 @@
+package apperrors
+
+import (
+	"api/src/i18n"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrForbidden  = errors.New("forbidden")
+	ErrValidation = errors.New("validation failed")
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	key     string
+	args    []interface{}
+}
+
+type Error struct {
+	kind   error
+	key    string
+	args   []interface{}
+	fields []FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Localize(i18n.DefaultLocale)
+}
+
+func (e *Error) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+func (e *Error) Kind() error {
+	return e.kind
+}
+
+func (e *Error) Fields() []FieldError {
+	return e.fields
+}
+
+func (e *Error) Localize(locale string) string {
+	if e.key == "" {
+		messages := make([]string, len(e.fields))
+		for i, field := range e.fields {
+			messages[i] = field.Localize(locale).Message
+		}
+		return strings.Join(messages, "; ")
+	}
+
+	return i18n.Translate(locale, e.key, e.args...)
+}
+
+func (f FieldError) Localize(locale string) FieldError {
+	if f.key != "" {
+		f.Message = i18n.Translate(locale, f.key, f.args...)
+	}
+	return f
+}
+
+func New(key string, args ...interface{}) error {
+	return &Error{key: key, args: args}
+}
+
+func NotFound(key string, args ...interface{}) error {
+	return &Error{kind: ErrNotFound, key: key, args: args}
+}
+
+func Conflict(key string, args ...interface{}) error {
+	return &Error{kind: ErrConflict, key: key, args: args}
+}
+
+func Forbidden(key string, args ...interface{}) error {
+	return &Error{kind: ErrForbidden, key: key, args: args}
+}
+
+func Validation(key string, args ...interface{}) error {
+	return &Error{kind: ErrValidation, key: key, args: args}
+}
+
+func Invalid(fields ...FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return &Error{kind: ErrValidation, fields: fields}
+}
+
+func Field(field, code, key string, args ...interface{}) FieldError {
+	return FieldError{
+		Field:   field,
+		Code:    code,
+		Message: i18n.Translate(i18n.DefaultLocale, key, args...),
+		key:     key,
+		args:    args,
+	}
+}
+
+func FieldErrors(err error) []FieldError {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.fields
+	}
+	return nil
+}
+
+func Localize(err error, locale string) (string, []FieldError) {
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		return err.Error(), nil
+	}
+
+	var fields []FieldError
+	for _, field := range appErr.fields {
+		fields = append(fields, field.Localize(locale))
+	}
+
+	return appErr.Localize(locale), fields
+}
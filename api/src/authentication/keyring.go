@@ -0,0 +1,232 @@
+package authentication
+
+import (
+	"api/src/config"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const legacyKeyID = "default"
+
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	retiredAt *time.Time
+}
+
+type keyring struct {
+	current *signingKey
+	keys    map[string]*signingKey
+	ordered []*signingKey
+}
+
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var keys *keyring
+
+func LoadKeys() error {
+	ring := &keyring{keys: map[string]*signingKey{}}
+
+	legacy := &signingKey{
+		id:        legacyKeyID,
+		method:    jwt.SigningMethodHS256,
+		signKey:   config.SecretKey,
+		verifyKey: config.SecretKey,
+	}
+	if len(config.SigningKeys) > 0 {
+		legacy.retiredAt = config.SecretKeyRetiredAt
+		if legacy.retiredAt == nil {
+			now := time.Now()
+			legacy.retiredAt = &now
+		}
+	}
+	if legacy.usable() {
+		ring.add(legacy)
+	}
+
+	for _, spec := range config.SigningKeys {
+		if _, exists := ring.keys[spec.ID]; exists {
+			return fmt.Errorf("signing key %q is declared more than once", spec.ID)
+		}
+
+		key, err := loadSigningKey(spec)
+		if err != nil {
+			return err
+		}
+
+		ring.add(key)
+		if ring.current == nil && key.retiredAt == nil {
+			ring.current = key
+		}
+	}
+
+	if ring.current == nil {
+		if len(config.SigningKeys) > 0 {
+			return errors.New("SIGNING_KEYS has no active key to sign tokens with")
+		}
+		ring.current = ring.keys[legacyKeyID]
+	}
+
+	keys = ring
+	return nil
+}
+
+func PublicKeys() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+
+	for _, key := range keys.ordered {
+		if !key.usable() {
+			continue
+		}
+
+		if jwk, ok := key.publicJWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	return set
+}
+
+func (k *keyring) add(key *signingKey) {
+	k.keys[key.id] = key
+	k.ordered = append(k.ordered, key)
+}
+
+func (k *keyring) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = legacyKeyID
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method! %v", token.Header["alg"])
+	}
+
+	if !key.usable() {
+		return nil, fmt.Errorf("signing key %q has been retired", kid)
+	}
+
+	return key.verifyKey, nil
+}
+
+func (s *signingKey) usable() bool {
+	if s.retiredAt == nil {
+		return true
+	}
+	return time.Now().Before(s.retiredAt.Add(config.SigningKeyGrace))
+}
+
+func (s *signingKey) publicJWK() (JSONWebKey, bool) {
+	jwk := JSONWebKey{KeyID: s.id, Use: "sig", Algorithm: s.method.Alg()}
+
+	switch publicKey := s.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return JSONWebKey{}, false
+	}
+
+	return jwk, true
+}
+
+func loadSigningKey(spec config.SigningKey) (*signingKey, error) {
+	data, err := os.ReadFile(spec.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key %q: %w", spec.ID, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %q is not PEM encoded", spec.ID)
+	}
+
+	privateKey, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %q: %w", spec.ID, err)
+	}
+
+	key := &signingKey{id: spec.ID, signKey: privateKey, retiredAt: spec.RetiredAt}
+
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		key.method = jwt.SigningMethodRS256
+		key.verifyKey = &privateKey.PublicKey
+	case *ecdsa.PrivateKey:
+		switch privateKey.Curve {
+		case elliptic.P256():
+			key.method = jwt.SigningMethodES256
+		case elliptic.P384():
+			key.method = jwt.SigningMethodES384
+		case elliptic.P521():
+			key.method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("signing key %q uses an unsupported curve", spec.ID)
+		}
+		key.verifyKey = &privateKey.PublicKey
+	case ed25519.PrivateKey:
+		key.method = SigningMethodEdDSA
+		key.verifyKey = privateKey.Public().(ed25519.PublicKey)
+	default:
+		return nil, fmt.Errorf("signing key %q has an unsupported key type", spec.ID)
+	}
+
+	return key, nil
+}
+
+func parsePrivateKey(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
@@ -0,0 +1,89 @@
+package authentication
+
+import (
+	"api/src/config"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeSigningKey(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "signing.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLegacyKeyRetirement(t *testing.T) {
+	defer func(secret []byte, signingKeys []config.SigningKey, retiredAt *time.Time, grace time.Duration) {
+		config.SecretKey, config.SigningKeys, config.SecretKeyRetiredAt, config.SigningKeyGrace = secret, signingKeys, retiredAt, grace
+	}(config.SecretKey, config.SigningKeys, config.SecretKeyRetiredAt, config.SigningKeyGrace)
+
+	config.SecretKey = []byte("legacy-secret")
+	config.SigningKeys = nil
+	config.SecretKeyRetiredAt = nil
+	config.SigningKeyGrace = time.Hour
+	if err := LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	legacyTokens, err := CreateToken(1, RoleUser, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.SigningKeys = []config.SigningKey{{ID: "k1", Path: writeSigningKey(t)}}
+	longAgo := time.Now().Add(-2 * time.Hour)
+
+	cases := []struct {
+		name      string
+		retiredAt *time.Time
+		accepted  bool
+	}{
+		{"retirement time unset", nil, true},
+		{"retired within the grace window", &[]time.Time{time.Now()}[0], true},
+		{"retired past the grace window", &longAgo, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.SecretKeyRetiredAt = tc.retiredAt
+			if err := LoadKeys(); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := ParseToken(legacyTokens.AccessToken); (err == nil) != tc.accepted {
+				t.Errorf("legacy token accepted=%v, want %v (err: %v)", err == nil, tc.accepted, err)
+			}
+
+			tokens, err := CreateToken(1, RoleUser, "session")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := ParseToken(tokens.AccessToken); err != nil {
+				t.Errorf("token signed with the new key rejected: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package authentication
+
+import (
+	"api/src/apperrors"
+	"context"
+	"time"
+)
+
+type Principal struct {
+	UserID    uint64
+	Role      string
+	TokenID   string
+	SessionID string
+	ClientID  string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+type principalContextKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, error) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+	if !ok {
+		return Principal{}, apperrors.New("auth.unauthenticated")
+	}
+	return principal, nil
+}
+
+func (p Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
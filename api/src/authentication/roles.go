@@ -0,0 +1,33 @@
+package authentication
+
+type Permission string
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+
+	PermissionModeratePublications Permission = "publications:moderate"
+	PermissionManageUsers          Permission = "users:manage"
+	PermissionManageRoles          Permission = "roles:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleUser:      {},
+	RoleModerator: {PermissionModeratePublications},
+	RoleAdmin:     {PermissionModeratePublications, PermissionManageUsers, PermissionManageRoles},
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func (p Principal) Can(permission Permission) bool {
+	for _, granted := range rolePermissions[p.Role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package authentication
+
+const (
+	ScopePublicationsRead  = "publications:read"
+	ScopePublicationsWrite = "publications:write"
+	ScopeUsersRead         = "users:read"
+	ScopeUsersWrite        = "users:write"
+	ScopeFollowsWrite      = "follows:write"
+	ScopeAccount           = "account"
+)
+
+var GrantableScopes = []string{
+	ScopePublicationsRead,
+	ScopePublicationsWrite,
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopeFollowsWrite,
+}
+
+var scopeDescriptions = map[string]string{
+	ScopePublicationsRead:  "Read publications",
+	ScopePublicationsWrite: "Create, edit, delete and like publications",
+	ScopeUsersRead:         "Read user profiles, followers and following",
+	ScopeUsersWrite:        "Edit your profile",
+	ScopeFollowsWrite:      "Follow and unfollow users",
+}
+
+var sessionScopes = append([]string{ScopeAccount}, GrantableScopes...)
+
+func IsGrantableScope(scope string) bool {
+	for _, grantable := range GrantableScopes {
+		if grantable == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func ScopeDescription(scope string) string {
+	return scopeDescriptions[scope]
+}
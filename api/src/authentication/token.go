@@ -1,8 +1,12 @@
 package authentication
 
 import (
+	"api/src/apperrors"
 	"api/src/config"
-	"errors"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -12,48 +16,249 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-func CreateToken(userID uint64) (string, error) {
+type TokenPair struct {
+	AccessToken      string    `json:"accessToken"`
+	RefreshToken     string    `json:"refreshToken"`
+	TokenType        string    `json:"tokenType"`
+	ExpiresIn        int64     `json:"expiresIn"`
+	RefreshExpiresAt time.Time `json:"-"`
+}
+
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+	ExpiresIn   int64  `json:"expiresIn"`
+}
+
+const (
+	accessTokenType = "access"
+	mfaTokenType    = "mfa"
+	magicTokenType  = "magic_link"
+
+	PersonalAccessTokenPrefix = "dbp_"
+)
+
+func CreateToken(userID uint64, role, sessionID string) (TokenPair, error) {
+	permissions := jwt.MapClaims{}
+	permissions["role"] = role
+
+	return createTokenPair(userID, sessionID, permissions)
+}
+
+func CreateClientToken(userID uint64, sessionID, clientID string, scopes []string) (TokenPair, error) {
 	permissions := jwt.MapClaims{}
+	permissions["role"] = RoleUser
+	permissions["cid"] = clientID
+	permissions["scope"] = strings.Join(scopes, " ")
+
+	return createTokenPair(userID, sessionID, permissions)
+}
+
+func createTokenPair(userID uint64, sessionID string, permissions jwt.MapClaims) (TokenPair, error) {
 	permissions["authorized"] = true
-	permissions["exp"] = time.Now().Add(time.Hour * 6).Unix()
+	permissions["typ"] = accessTokenType
+	permissions["exp"] = time.Now().Add(config.AccessTokenTTL).Unix()
 	permissions["userID"] = userID
+	permissions["sid"] = sessionID
+
+	tokenID, err := NewID()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	permissions["jti"] = tokenID
+
+	accessToken, err := sign(permissions)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	refreshToken, err := GenerateOpaqueToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, permissions)
-	return token.SignedString(config.SecretKey)
+	return TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(config.AccessTokenTTL.Seconds()),
+		RefreshExpiresAt: time.Now().UTC().Add(config.RefreshTokenTTL),
+	}, nil
 }
 
-func ValidateToken(r *http.Request) error {
-	tokenStr := extractToken(r)
-	token, err := jwt.Parse(tokenStr, getKeyValidate)
+func CreateMFAToken(userID uint64, challengeID string) (MFAChallenge, error) {
+	permissions := jwt.MapClaims{}
+	permissions["typ"] = mfaTokenType
+	permissions["exp"] = time.Now().Add(config.MFATokenTTL).Unix()
+	permissions["userID"] = userID
+	permissions["jti"] = challengeID
+
+	mfaToken, err := sign(permissions)
+	if err != nil {
+		return MFAChallenge{}, err
+	}
+
+	return MFAChallenge{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		ExpiresIn:   int64(config.MFATokenTTL.Seconds()),
+	}, nil
+}
+
+func ParseMFAToken(tokenStr string) (uint64, string, error) {
+	permissions, err := parseClaims(tokenStr)
 	if err != nil {
-		return err
+		return 0, "", err
+	}
+
+	if permissions["typ"] != mfaTokenType {
+		return 0, "", apperrors.New("auth.token_not_mfa")
 	}
 
-	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return nil
+	challengeID, ok := permissions["jti"].(string)
+	if !ok || challengeID == "" {
+		return 0, "", apperrors.New("two_factor.challenge_invalid")
 	}
-	return errors.New("token is invalid")
+
+	userID, err := extractUserID(permissions)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return userID, challengeID, nil
 }
 
-func ExtractUserID(r *http.Request) (uint64, error) {
-	tokenStr := extractToken(r)
+func CreateMagicLinkToken(userID uint64, linkID string) (string, error) {
+	permissions := jwt.MapClaims{}
+	permissions["typ"] = magicTokenType
+	permissions["exp"] = time.Now().Add(config.MagicLinkTTL).Unix()
+	permissions["userID"] = userID
+	permissions["jti"] = linkID
+
+	return sign(permissions)
+}
+
+func ParseMagicLinkToken(tokenStr string) (uint64, string, error) {
+	permissions, err := parseClaims(tokenStr)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if permissions["typ"] != magicTokenType {
+		return 0, "", apperrors.New("magic_link.not_magic_link")
+	}
+
+	linkID, ok := permissions["jti"].(string)
+	if !ok || linkID == "" {
+		return 0, "", apperrors.New("magic_link.no_identifier")
+	}
+
+	userID, err := extractUserID(permissions)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return userID, linkID, nil
+}
+
+func NewID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func GenerateOpaqueToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+func GeneratePersonalAccessToken() (string, error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalAccessTokenPrefix + token, nil
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func ParseToken(tokenStr string) (Principal, error) {
+	permissions, err := parseClaims(tokenStr)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	if typ, ok := permissions["typ"]; ok && typ != accessTokenType {
+		return Principal{}, apperrors.New("auth.token_not_access")
+	}
+
+	userID, err := extractUserID(permissions)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	sessionID, ok := permissions["sid"].(string)
+	if !ok || sessionID == "" {
+		return Principal{}, apperrors.New("auth.token_no_session")
+	}
+
+	principal := Principal{UserID: userID, Role: RoleUser, SessionID: sessionID}
+	if role, ok := permissions["role"].(string); ok && IsValidRole(role) {
+		principal.Role = role
+	}
+	principal.TokenID, _ = permissions["jti"].(string)
+	principal.ClientID, _ = permissions["cid"].(string)
+
+	if exp, ok := permissions["exp"].(float64); ok {
+		principal.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+
+	if scope, ok := permissions["scope"].(string); ok {
+		principal.Scopes = strings.Fields(scope)
+	} else {
+		principal.Scopes = sessionScopes
+	}
+
+	return principal, nil
+}
+
+func sign(permissions jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(keys.current.method, permissions)
+	token.Header["kid"] = keys.current.id
+	return token.SignedString(keys.current.signKey)
+}
+
+func parseClaims(tokenStr string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenStr, getKeyValidate)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if permissions, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, err := strconv.ParseUint(fmt.Sprintf("%.0f", permissions["userID"]), 10, 64)
-		if err != nil {
-			return 0, err
-		}
-		return userID, nil
+	permissions, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, apperrors.New("auth.token_invalid")
 	}
 
-	return 0, errors.New("token is invalid")
+	return permissions, nil
+}
+
+func extractUserID(permissions jwt.MapClaims) (uint64, error) {
+	return strconv.ParseUint(fmt.Sprintf("%.0f", permissions["userID"]), 10, 64)
 }
 
-func extractToken(r *http.Request) string {
+func ExtractToken(r *http.Request) string {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader != "" && strings.HasPrefix(authHeader, "Bearer ") {
 		return strings.TrimPrefix(authHeader, "Bearer ")
@@ -63,9 +268,5 @@ func extractToken(r *http.Request) string {
 }
 
 func getKeyValidate(token *jwt.Token) (interface{}, error) {
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		return nil, fmt.Errorf("unexpected signing method! %v", token.Header["alg"])
-	}
-
-	return config.SecretKey, nil
+	return keys.verificationKey(token)
 }
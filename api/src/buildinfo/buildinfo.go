@@ -0,0 +1,38 @@
+package buildinfo
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+var startedAt = time.Now()
+
+func init() {
+	if Commit != "" {
+		return
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			Commit = setting.Value
+		}
+	}
+}
+
+func StartedAt() time.Time {
+	return startedAt
+}
+
+func Uptime() time.Duration {
+	return time.Since(startedAt)
+}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"api/src/ratelimit"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -14,8 +19,98 @@ var (
 	DBConnectionString string
 	Port               int
 	SecretKey          []byte
+	SecretKeyRetiredAt *time.Time
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	SigningKeys        []SigningKey
+	MFATokenTTL        time.Duration
+	MFAMaxAttempts     int
+	TOTPIssuer         string
+	SigningKeyGrace    time.Duration
+	AppURL             string
+	PasswordResetTTL   time.Duration
+	EmailVerifyTTL     time.Duration
+	RequireVerified    bool
+	LoginMaxAttempts   int
+	LoginIPMaxAttempts int
+	LoginLockout       time.Duration
+	LoginBackoffBase   time.Duration
+	MailDriver         string
+	MailFrom           string
+	MailLogPath        string
+	SMTPHost           string
+	SMTPPort           string
+	SMTPUsername       string
+	SMTPPassword       string
+	OIDCProviders      []OIDCProvider
+	OIDCStateTTL       time.Duration
+	OAuthCodeTTL       time.Duration
+	PasswordHasher     string
+	Argon2Memory       int
+	Argon2Iterations   int
+	Argon2Parallelism  int
+	BcryptCost         int
+	PasswordMinLength  int
+	BreachedPasswords  string
+	MagicLinkTTL       time.Duration
+	MagicLinkMax       int
+	MagicLinkWindow    time.Duration
+	ErrorFormat        string
+	PageDefaultLimit   int
+	PageMaxLimit       int
+	ReadHeaderTimeout  time.Duration
+	ReadTimeout        time.Duration
+	WriteTimeout       time.Duration
+	IdleTimeout        time.Duration
+	ShutdownTimeout    time.Duration
+	ShutdownDrainDelay time.Duration
+	ReadinessTimeout   time.Duration
+	LogLevel           string
+	LogFormat          string
+	TraceExporter      string
+	TraceOTLPEndpoint  string
+	TraceFile          string
+	TraceSampleRatio   float64
+	TraceServiceName   string
+	RateLimitStore     string
+	RateLimitOnError   string
+	RateLimitDefault   ratelimit.Limit
+	RateLimitIP        ratelimit.Limit
+	RateLimitAuth      ratelimit.Limit
+	RateLimitSignup    ratelimit.Limit
+	RateLimitPublish   ratelimit.Limit
+	TrustedProxies     []*net.IPNet
+	CORSOrigins        []string
+	CORSMethods        []string
+	CORSHeaders        []string
+	CORSExposedHeaders []string
+	CORSCredentials    bool
+	CORSMaxAge         time.Duration
+	HSTSMaxAge         time.Duration
+	ContentSecurity    string
+	MaxBodyBytes       int64
 )
 
+type OIDCProvider struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	EmailsURL    string
+	JWKSURL      string
+	Scopes       []string
+}
+
+type SigningKey struct {
+	ID        string
+	Path      string
+	RetiredAt *time.Time
+}
+
 func Load() {
 	var err error
 
@@ -51,4 +146,328 @@ func Load() {
 	if len(SecretKey) == 0 {
 		log.Fatal("SECRET_KEY is missing or empty")
 	}
+
+	AccessTokenTTL = loadDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+	RefreshTokenTTL = loadDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+
+	SigningKeys, err = parseSigningKeys(os.Getenv("SIGNING_KEYS"))
+	if err != nil {
+		log.Fatal("Invalid SIGNING_KEYS: ", err)
+	}
+	SigningKeyGrace = loadDuration("SIGNING_KEY_GRACE", 24*time.Hour)
+
+	if value := os.Getenv("SECRET_KEY_RETIRED_AT"); value != "" {
+		retiredAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			log.Fatal("Invalid SECRET_KEY_RETIRED_AT: ", err)
+		}
+		SecretKeyRetiredAt = &retiredAt
+	}
+
+	MFATokenTTL = loadDuration("MFA_TOKEN_TTL", 5*time.Minute)
+	MFAMaxAttempts = loadInt("MFA_MAX_ATTEMPTS", 5)
+	TOTPIssuer = os.Getenv("TOTP_ISSUER")
+	if TOTPIssuer == "" {
+		TOTPIssuer = "DevBook"
+	}
+
+	AppURL = os.Getenv("APP_URL")
+	if AppURL == "" {
+		AppURL = fmt.Sprintf("http://localhost:%d", Port)
+	}
+	PasswordResetTTL = loadDuration("PASSWORD_RESET_TTL", time.Hour)
+	MagicLinkTTL = loadDuration("MAGIC_LINK_TTL", 15*time.Minute)
+	MagicLinkMax = loadInt("MAGIC_LINK_MAX_REQUESTS", 5)
+	MagicLinkWindow = loadDuration("MAGIC_LINK_WINDOW", time.Hour)
+	EmailVerifyTTL = loadDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	RequireVerified, _ = strconv.ParseBool(os.Getenv("REQUIRE_VERIFIED_EMAIL"))
+
+	LoginMaxAttempts = loadInt("LOGIN_MAX_ATTEMPTS", 5)
+	LoginIPMaxAttempts = loadInt("LOGIN_IP_MAX_ATTEMPTS", 50)
+	LoginLockout = loadDuration("LOGIN_LOCKOUT", 15*time.Minute)
+	LoginBackoffBase = loadDuration("LOGIN_BACKOFF_BASE", time.Second)
+
+	MailDriver = os.Getenv("MAIL_DRIVER")
+	MailFrom = os.Getenv("MAIL_FROM")
+	if MailFrom == "" {
+		MailFrom = "no-reply@devbook.local"
+	}
+	MailLogPath = os.Getenv("MAIL_LOG_PATH")
+	SMTPHost = os.Getenv("SMTP_HOST")
+	SMTPPort = os.Getenv("SMTP_PORT")
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+
+	if MailDriver == "smtp" && (SMTPHost == "" || SMTPPort == "") {
+		log.Fatal("SMTP_HOST and SMTP_PORT are required when MAIL_DRIVER is smtp")
+	}
+
+	OIDCProviders, err = loadOIDCProviders(os.Getenv("OIDC_PROVIDERS"))
+	if err != nil {
+		log.Fatal("Invalid OIDC_PROVIDERS: ", err)
+	}
+	OIDCStateTTL = loadDuration("OIDC_STATE_TTL", 10*time.Minute)
+	OAuthCodeTTL = loadDuration("OAUTH_CODE_TTL", time.Minute)
+
+	PasswordHasher = os.Getenv("PASSWORD_HASHER")
+	if PasswordHasher == "" {
+		PasswordHasher = "argon2id"
+	}
+	Argon2Memory = loadInt("ARGON2_MEMORY_KIB", 64*1024)
+	Argon2Iterations = loadInt("ARGON2_ITERATIONS", 3)
+	Argon2Parallelism = loadInt("ARGON2_PARALLELISM", 2)
+	BcryptCost = loadInt("BCRYPT_COST", 10)
+	PasswordMinLength = loadInt("PASSWORD_MIN_LENGTH", 8)
+	BreachedPasswords = os.Getenv("BREACHED_PASSWORDS_PATH")
+
+	LogLevel = os.Getenv("LOG_LEVEL")
+	if LogLevel == "" {
+		LogLevel = "info"
+	}
+	LogFormat = os.Getenv("LOG_FORMAT")
+	if LogFormat == "" {
+		LogFormat = "json"
+	}
+
+	RateLimitStore = os.Getenv("RATE_LIMIT_STORE")
+	if RateLimitStore == "" {
+		RateLimitStore = "memory"
+	}
+	RateLimitOnError = os.Getenv("RATE_LIMIT_ON_ERROR")
+	if RateLimitOnError == "" {
+		RateLimitOnError = "allow"
+	}
+	if RateLimitOnError != "allow" && RateLimitOnError != "deny" {
+		log.Fatal("RATE_LIMIT_ON_ERROR must be allow or deny")
+	}
+	RateLimitDefault = loadLimit("RATE_LIMIT_DEFAULT", ratelimit.Limit{Requests: 300, Window: time.Minute})
+	RateLimitIP = loadLimit("RATE_LIMIT_IP", ratelimit.Limit{Requests: 600, Window: time.Minute})
+	RateLimitAuth = loadLimit("RATE_LIMIT_AUTH", ratelimit.Limit{Requests: 10, Window: time.Minute})
+	RateLimitSignup = loadLimit("RATE_LIMIT_SIGNUP", ratelimit.Limit{Requests: 5, Window: time.Hour})
+	RateLimitPublish = loadLimit("RATE_LIMIT_PUBLISH", ratelimit.Limit{Requests: 30, Window: time.Minute})
+
+	TrustedProxies, err = parseCIDRs(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
+
+	CORSOrigins = loadList("CORS_ALLOWED_ORIGINS", nil)
+	CORSMethods = loadList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE"})
+	CORSHeaders = loadList("CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "Accept", "Accept-Language", "X-Request-ID", "traceparent"})
+	CORSExposedHeaders = loadList("CORS_EXPOSED_HEADERS", []string{
+		"Content-Language", "Link", "X-Next-Cursor", "Retry-After", "X-Request-ID", "traceparent",
+		"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+	})
+	CORSCredentials, _ = strconv.ParseBool(os.Getenv("CORS_ALLOW_CREDENTIALS"))
+	CORSMaxAge = loadDuration("CORS_MAX_AGE", 10*time.Minute)
+
+	if CORSCredentials && slices.Contains(CORSOrigins, "*") {
+		log.Fatal("CORS_ALLOWED_ORIGINS cannot contain * when CORS_ALLOW_CREDENTIALS is enabled")
+	}
+
+	HSTSMaxAge = loadDuration("HSTS_MAX_AGE", 180*24*time.Hour)
+	ContentSecurity = os.Getenv("CONTENT_SECURITY_POLICY")
+	if ContentSecurity == "" {
+		ContentSecurity = "default-src 'none'; frame-ancestors 'none'"
+	}
+	MaxBodyBytes = int64(loadInt("MAX_BODY_BYTES", 1<<20))
+
+	TraceExporter = os.Getenv("TRACE_EXPORTER")
+	if TraceExporter == "" {
+		TraceExporter = "none"
+	}
+	TraceOTLPEndpoint = os.Getenv("TRACE_OTLP_ENDPOINT")
+	TraceFile = os.Getenv("TRACE_FILE")
+	TraceSampleRatio = loadRatio("TRACE_SAMPLE_RATIO", 1)
+	TraceServiceName = os.Getenv("TRACE_SERVICE_NAME")
+	if TraceServiceName == "" {
+		TraceServiceName = "devbook-api"
+	}
+
+	ReadHeaderTimeout = loadDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second)
+	ReadTimeout = loadDuration("HTTP_READ_TIMEOUT", 15*time.Second)
+	WriteTimeout = loadDuration("HTTP_WRITE_TIMEOUT", 30*time.Second)
+	IdleTimeout = loadDuration("HTTP_IDLE_TIMEOUT", 2*time.Minute)
+	ShutdownTimeout = loadDuration("SHUTDOWN_TIMEOUT", 20*time.Second)
+	ShutdownDrainDelay = loadDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second)
+	ReadinessTimeout = loadDuration("READINESS_TIMEOUT", 2*time.Second)
+
+	PageMaxLimit = loadInt("PAGE_MAX_LIMIT", 100)
+	PageDefaultLimit = min(loadInt("PAGE_DEFAULT_LIMIT", 20), PageMaxLimit)
+
+	ErrorFormat = os.Getenv("ERROR_FORMAT")
+	if ErrorFormat != "legacy" {
+		ErrorFormat = "problem"
+	}
+}
+
+func loadInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		log.Printf("Invalid %s, defaulting to %d: %v", key, fallback, err)
+		return fallback
+	}
+
+	return number
+}
+
+func loadRatio(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		log.Printf("Invalid %s, defaulting to %g: %v", key, fallback, err)
+		return fallback
+	}
+
+	return ratio
+}
+
+func loadList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func loadLimit(key string, fallback ratelimit.Limit) ratelimit.Limit {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		log.Printf("Invalid %s, defaulting to %d/%s: %v", key, fallback.Requests, fallback.Window, err)
+		return fallback
+	}
+
+	return limit
+}
+
+func parseCIDRs(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("entry %q is not an IP address or CIDR range", entry)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+func parseSigningKeys(value string) ([]SigningKey, error) {
+	var keys []SigningKey
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		spec, retiredAt, retired := strings.Cut(entry, "@")
+		id, path, ok := strings.Cut(spec, ":")
+		if !ok || id == "" || path == "" {
+			return nil, fmt.Errorf("entry %q must be in the form kid:path[@retiredAt]", entry)
+		}
+
+		key := SigningKey{ID: id, Path: path}
+		if retired {
+			t, err := time.Parse(time.RFC3339, retiredAt)
+			if err != nil {
+				return nil, fmt.Errorf("key %q has an invalid retirement time: %w", id, err)
+			}
+			key.RetiredAt = &t
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func loadOIDCProviders(value string) ([]OIDCProvider, error) {
+	var providers []OIDCProvider
+
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProvider{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER_URL"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			AuthURL:      os.Getenv(prefix + "AUTH_URL"),
+			TokenURL:     os.Getenv(prefix + "TOKEN_URL"),
+			UserInfoURL:  os.Getenv(prefix + "USERINFO_URL"),
+			EmailsURL:    os.Getenv(prefix + "EMAILS_URL"),
+			JWKSURL:      os.Getenv(prefix + "JWKS_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+
+		if provider.ClientID == "" || provider.ClientSecret == "" {
+			return nil, fmt.Errorf("provider %q requires %sCLIENT_ID and %sCLIENT_SECRET", name, prefix, prefix)
+		}
+
+		if provider.RedirectURL == "" {
+			provider.RedirectURL = fmt.Sprintf("%s/login/oidc/%s/callback", AppURL, name)
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+func loadDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Invalid %s, defaulting to %s: %v", key, fallback, err)
+		return fallback
+	}
+
+	return duration
 }
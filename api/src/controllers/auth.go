@@ -1,53 +1,782 @@
 package controllers
 
 import (
+	"api/src/apperrors"
 	"api/src/authentication"
+	"api/src/config"
+	"api/src/logging"
+	"api/src/mail"
+	"api/src/metrics"
 	"api/src/models"
 	"api/src/repositories"
+	"api/src/requests"
 	"api/src/responses"
 	"api/src/security"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const magicLinkCookie = "devbook_magic_nonce"
+
+var (
+	errInvalidCredentials = apperrors.New("auth.invalid_credentials")
+	errTooManyAttempts    = apperrors.New("auth.too_many_attempts")
+	errTooManyMagicLinks  = apperrors.New("magic_link.too_many")
+	errInvalidMagicLink   = apperrors.New("magic_link.invalid")
+	errInvalidChallenge   = apperrors.New("two_factor.challenge_invalid")
 )
 
 type AuthController struct {
-	repository repositories.UserRepository
+	repository              repositories.UserRepository
+	tokenRepository         repositories.TokenRepository
+	passwordResetRepository repositories.PasswordResetRepository
+	twoFactorRepository     repositories.TwoFactorRepository
+	loginAttemptRepository  repositories.LoginAttemptRepository
+	securityEventRepository repositories.SecurityEventRepository
+	sessionRepository       repositories.SessionRepository
+	magicLinkRepository     repositories.MagicLinkRepository
+	mailer                  mail.Sender
+	metrics                 *metrics.Metrics
+	logger                  *slog.Logger
+	dummyPasswordHash       string
 }
 
-func NewAuthController(repository repositories.UserRepository) *AuthController {
-	return &AuthController{repository: repository}
+func NewAuthController(
+	repository repositories.UserRepository,
+	tokenRepository repositories.TokenRepository,
+	passwordResetRepository repositories.PasswordResetRepository,
+	twoFactorRepository repositories.TwoFactorRepository,
+	loginAttemptRepository repositories.LoginAttemptRepository,
+	securityEventRepository repositories.SecurityEventRepository,
+	sessionRepository repositories.SessionRepository,
+	magicLinkRepository repositories.MagicLinkRepository,
+	mailer mail.Sender,
+	metrics *metrics.Metrics,
+	logger *slog.Logger,
+) *AuthController {
+	dummyPasswordHash, _ := security.Hash("devbook-dummy-password")
+
+	return &AuthController{
+		repository:              repository,
+		tokenRepository:         tokenRepository,
+		passwordResetRepository: passwordResetRepository,
+		twoFactorRepository:     twoFactorRepository,
+		loginAttemptRepository:  loginAttemptRepository,
+		securityEventRepository: securityEventRepository,
+		sessionRepository:       sessionRepository,
+		magicLinkRepository:     magicLinkRepository,
+		mailer:                  mailer,
+		metrics:                 metrics,
+		logger:                  logger,
+		dummyPasswordHash:       string(dummyPasswordHash),
+	}
 }
 
 func (a AuthController) Login(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		responses.Err(w, http.StatusUnprocessableEntity, err)
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
 		return
 	}
 
 	var user models.User
 	if err := json.Unmarshal(body, &user); err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ip := requests.ClientIP(r)
+	accountKey := "account:" + strings.ToLower(strings.TrimSpace(user.Email))
+	ipKey := "ip:" + ip
+
+	if a.rejectBlocked(w, r, accountKey, ipKey) {
+		return
+	}
+
+	saveUser, err := a.repository.GetUserByEmail(r.Context(), user.Email)
+	if err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+		responses.Error(w, r, err)
+		return
+	}
+
+	storedHash := saveUser.Password
+	if saveUser.ID == 0 {
+		storedHash = a.dummyPasswordHash
+	}
+
+	if err := security.ValidatePassword(storedHash, user.Password); err != nil || saveUser.ID == 0 {
+		if err := a.recordLoginFailure(r.Context(), saveUser.ID, ip, accountKey, config.LoginMaxAttempts, true); err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+
+		if err := a.recordLoginFailure(r.Context(), saveUser.ID, ip, ipKey, config.LoginIPMaxAttempts, false); err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+
+		a.metrics.LoginFailed()
+		responses.Err(w, r, http.StatusUnauthorized, errInvalidCredentials)
+		return
+	}
+
+	if err := a.loginAttemptRepository.Reset(r.Context(), accountKey); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if security.NeedsRehash(storedHash) {
+		a.rehashPassword(r.Context(), saveUser.ID, user.Password)
+	}
+
+	a.completeLogin(w, r, saveUser.ID)
+}
+
+func (a AuthController) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.TwoFactorLogin
+	if err := json.Unmarshal(body, &request); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, challengeID, err := authentication.ParseMFAToken(request.MFAToken)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	mfaKey := mfaAttemptKey(userID)
+	if a.rejectBlocked(w, r, mfaKey) {
+		return
+	}
+
+	active, err := a.twoFactorRepository.ChallengeActive(r.Context(), challengeID, userID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if !active {
+		responses.Err(w, r, http.StatusUnauthorized, errInvalidChallenge)
+		return
+	}
+
+	twoFactor, err := a.twoFactorRepository.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if twoFactor.EnabledAt == nil {
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("two_factor.not_enabled"))
 		return
 	}
 
-	saveUser, err := a.repository.GetUserByEmail(user.Email)
+	valid, err := verifySecondFactor(r.Context(), a.twoFactorRepository, userID, twoFactor, request.Code, request.RecoveryCode)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
 		return
 	}
 
-	if err := security.ValidatePassword(saveUser.Password, user.Password); err != nil {
-		responses.Err(w, http.StatusUnauthorized, err)
+	if !valid {
+		if _, err := a.twoFactorRepository.RecordChallengeFailure(r.Context(), challengeID, config.MFAMaxAttempts); err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+
+		if err := a.recordLoginFailure(r.Context(), userID, requests.ClientIP(r), mfaKey, config.MFAMaxAttempts, true); err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+
+		a.metrics.LoginFailed()
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("two_factor.code_invalid"))
 		return
 	}
 
-	token, err := authentication.CreateToken(saveUser.ID)
+	consumed, err := a.twoFactorRepository.ConsumeChallenge(r.Context(), challengeID)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
+		return
+	}
+
+	if !consumed {
+		responses.Err(w, r, http.StatusUnauthorized, errInvalidChallenge)
+		return
+	}
+
+	if err := a.loginAttemptRepository.Reset(r.Context(), mfaKey); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	a.startSession(w, r, userID)
+}
+
+func (a AuthController) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.MagicLinkRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	email := strings.TrimSpace(request.Email)
+	if email == "" {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("auth.email_required"))
+		return
+	}
+
+	key := "magic:" + strings.ToLower(email)
+	blockedUntil, err := a.loginAttemptRepository.GetBlockedUntil(r.Context(), key)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if wait := time.Until(blockedUntil); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		responses.Err(w, r, http.StatusTooManyRequests, errTooManyMagicLinks)
+		return
+	}
+
+	requested, err := a.loginAttemptRepository.RecordFailure(r.Context(), key, config.MagicLinkWindow)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if requested > config.MagicLinkMax {
+		if err := a.loginAttemptRepository.Block(r.Context(), key, time.Now().UTC().Add(config.MagicLinkWindow)); err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(config.MagicLinkWindow.Seconds())))
+		responses.Err(w, r, http.StatusTooManyRequests, errTooManyMagicLinks)
+		return
+	}
+
+	nonce, err := authentication.GenerateOpaqueToken()
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	setLoginCookie(w, magicLinkCookie, "/login/magic", nonce, time.Now().UTC().Add(config.MagicLinkTTL))
+
+	go a.sendMagicLink(context.WithoutCancel(r.Context()), email, nonce)
+
+	responses.JSON(w, http.StatusAccepted, models.MagicLinkIssued{
+		Nonce:     nonce,
+		ExpiresIn: int64(config.MagicLinkTTL.Seconds()),
+	})
+}
+
+func (a AuthController) VerifyMagicLink(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.MagicLinkVerify
+	if err := json.Unmarshal(body, &request); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.Nonce == "" {
+		if cookie, err := r.Cookie(magicLinkCookie); err == nil {
+			request.Nonce = cookie.Value
+		}
+	}
+
+	if request.Token == "" || request.Nonce == "" {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("magic_link.required"))
+		return
+	}
+
+	userID, linkID, err := authentication.ParseMagicLinkToken(request.Token)
+	if err != nil {
+		a.metrics.LoginFailed()
+		responses.Err(w, r, http.StatusUnauthorized, errInvalidMagicLink)
+		return
+	}
+
+	consumed, err := a.magicLinkRepository.ConsumeMagicLink(r.Context(), linkID, userID, authentication.HashToken(request.Nonce))
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if !consumed {
+		a.metrics.LoginFailed()
+		responses.Err(w, r, http.StatusUnauthorized, errInvalidMagicLink)
+		return
+	}
+
+	setLoginCookie(w, magicLinkCookie, "/login/magic", "", time.Unix(0, 0))
+
+	a.completeLogin(w, r, userID)
+}
+
+func (a AuthController) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.TokenRefresh
+	if err := json.Unmarshal(body, &request); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.RefreshToken == "" {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("auth.refresh_required"))
+		return
+	}
+
+	savedToken, err := a.tokenRepository.GetRefreshToken(r.Context(), authentication.HashToken(request.RefreshToken))
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if savedToken.ID == 0 {
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("auth.refresh_invalid"))
+		return
+	}
+
+	session, err := a.sessionRepository.GetSession(r.Context(), savedToken.SessionID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if session.ClientID != "" {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("auth.refresh_oauth_client"))
+		return
+	}
+
+	if savedToken.UsedAt != nil || savedToken.RevokedAt != nil {
+		a.revokeReusedSession(w, r, savedToken)
+		return
+	}
+
+	if time.Now().UTC().After(savedToken.ExpiresAt) {
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("auth.refresh_expired"))
+		return
+	}
+
+	rotated, err := a.tokenRepository.MarkRefreshTokenUsed(r.Context(), savedToken.ID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if !rotated {
+		a.revokeReusedSession(w, r, savedToken)
+		return
+	}
+
+	tokens, err := a.issueTokens(r.Context(), savedToken.UserID, savedToken.SessionID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, tokens)
+}
+
+func (a AuthController) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, err := authentication.PrincipalFromContext(r.Context())
+	if err != nil {
+		responses.Err(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	if _, err := a.sessionRepository.RevokeSession(r.Context(), principal.UserID, principal.SessionID); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func (a AuthController) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	responses.JSON(w, http.StatusOK, authentication.PublicKeys())
+}
+
+func (a AuthController) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.PasswordForgot
+	if err := json.Unmarshal(body, &request); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	go a.sendPasswordReset(context.WithoutCancel(r.Context()), request.Email)
+
+	responses.JSON(w, http.StatusAccepted, nil)
+}
+
+func (a AuthController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.PasswordReset
+	if err := json.Unmarshal(body, &request); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.Token == "" || request.New == "" {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("auth.reset_required"))
+		return
+	}
+
+	if err := security.CheckPasswordPolicy(request.New); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	hashedPassword, err := security.Hash(request.New)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := a.passwordResetRepository.ConsumePasswordReset(r.Context(), authentication.HashToken(request.Token))
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if userID == 0 {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("auth.reset_invalid"))
+		return
+	}
+
+	if err := a.repository.UpdatePassword(r.Context(), userID, string(hashedPassword)); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if err := a.sessionRepository.RevokeOtherSessions(r.Context(), userID, ""); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func (a AuthController) sendPasswordReset(ctx context.Context, email string) {
+	logger := logging.FromContext(ctx, a.logger)
+
+	saveUser, err := a.repository.GetUserByEmail(ctx, email)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return
+	}
+
+	if err != nil {
+		logger.Error("password reset: failed to look up user", slog.Any("error", err))
+		return
+	}
+
+	token, err := authentication.GenerateOpaqueToken()
+	if err != nil {
+		logger.Error("password reset: failed to generate token", slog.Any("error", err))
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(config.PasswordResetTTL)
+	if err := a.passwordResetRepository.CreatePasswordReset(ctx, saveUser.ID, authentication.HashToken(token), expiresAt); err != nil {
+		logger.Error("password reset: failed to store token", slog.Any("error", err))
+		return
+	}
+
+	link := fmt.Sprintf("%s/password/reset?token=%s", config.AppURL, url.QueryEscape(token))
+	if err := a.mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Reset your DevBook password",
+		Body: fmt.Sprintf(
+			"Someone requested a password reset for your DevBook account.\n\n"+
+				"Use the link below within %s to choose a new password:\n\n%s\n\n"+
+				"If you did not request it, you can ignore this email.",
+			config.PasswordResetTTL, link,
+		),
+	}); err != nil {
+		logger.Error("password reset: failed to send email", slog.Any("error", err))
+	}
+}
+
+func (a AuthController) sendMagicLink(ctx context.Context, email, nonce string) {
+	logger := logging.FromContext(ctx, a.logger)
+
+	saveUser, err := a.repository.GetUserByEmail(ctx, email)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return
+	}
+
+	if err != nil {
+		logger.Error("magic link: failed to look up user", slog.Any("error", err))
+		return
+	}
+
+	linkID, err := authentication.NewID()
+	if err != nil {
+		logger.Error("magic link: failed to generate id", slog.Any("error", err))
+		return
+	}
+
+	if err := a.magicLinkRepository.CreateMagicLink(ctx, models.MagicLink{
+		ID:        linkID,
+		UserID:    saveUser.ID,
+		NonceHash: authentication.HashToken(nonce),
+		ExpiresAt: time.Now().UTC().Add(config.MagicLinkTTL),
+	}); err != nil {
+		logger.Error("magic link: failed to store link", slog.Any("error", err))
+		return
+	}
+
+	token, err := authentication.CreateMagicLinkToken(saveUser.ID, linkID)
+	if err != nil {
+		logger.Error("magic link: failed to sign link", slog.Any("error", err))
+		return
+	}
+
+	link := fmt.Sprintf("%s/login/magic?token=%s", config.AppURL, url.QueryEscape(token))
+	if err := a.mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Your DevBook sign-in link",
+		Body: fmt.Sprintf(
+			"Use the link below within %s to sign in to DevBook.\n\n%s\n\n"+
+				"The link only works once, on the device where it was requested. "+
+				"If you did not request it, you can ignore this email.",
+			config.MagicLinkTTL, link,
+		),
+	}); err != nil {
+		logger.Error("magic link: failed to send email", slog.Any("error", err))
+	}
+}
+
+func (a AuthController) rehashPassword(ctx context.Context, userID uint64, password string) {
+	logger := logging.FromContext(ctx, a.logger)
+
+	hashedPassword, err := security.Hash(password)
+	if err != nil {
+		logger.Error("login: failed to rehash password", slog.Any("error", err))
+		return
+	}
+
+	if err := a.repository.UpdatePassword(ctx, userID, string(hashedPassword)); err != nil {
+		logger.Error("login: failed to store rehashed password", slog.Any("error", err))
+	}
+}
+
+func (a AuthController) rejectBlocked(w http.ResponseWriter, r *http.Request, keys ...string) bool {
+	blockedUntil, err := a.loginAttemptRepository.GetBlockedUntil(r.Context(), keys...)
+	if err != nil {
+		responses.Error(w, r, err)
+		return true
+	}
+
+	if wait := time.Until(blockedUntil); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		responses.Err(w, r, http.StatusTooManyRequests, errTooManyAttempts)
+		return true
+	}
+
+	return false
+}
+
+func (a AuthController) recordLoginFailure(ctx context.Context, userID uint64, ip, key string, threshold int, backoff bool) error {
+	failures, err := a.loginAttemptRepository.RecordFailure(ctx, key, config.LoginLockout)
+	if err != nil {
+		return err
+	}
+
+	var delay time.Duration
+	if backoff {
+		delay = loginBackoff(failures)
+	}
+	if failures >= threshold {
+		delay = config.LoginLockout
+
+		if err := a.securityEventRepository.Record(ctx, models.SecurityEvent{
+			UserID: userID,
+			Type:   models.EventLoginLockout,
+			IP:     ip,
+			Detail: fmt.Sprintf("%s locked for %s after %d failed attempts", key, delay, failures),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if delay <= 0 {
+		return nil
+	}
+
+	return a.loginAttemptRepository.Block(ctx, key, time.Now().UTC().Add(delay))
+}
+
+func mfaAttemptKey(userID uint64) string {
+	return "mfa:" + strconv.FormatUint(userID, 10)
+}
+
+func loginBackoff(failures int) time.Duration {
+	if failures <= 1 {
+		return 0
+	}
+
+	if failures > 32 {
+		return config.LoginLockout
+	}
+
+	delay := config.LoginBackoffBase << (failures - 2)
+	if delay <= 0 || delay > config.LoginLockout {
+		return config.LoginLockout
+	}
+
+	return delay
+}
+
+func (a AuthController) completeLogin(w http.ResponseWriter, r *http.Request, userID uint64) {
+	twoFactor, err := a.twoFactorRepository.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if twoFactor.EnabledAt != nil {
+		challengeID, err := authentication.NewID()
+		if err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+
+		if err := a.twoFactorRepository.CreateChallenge(r.Context(), models.TwoFactorChallenge{
+			ID:        challengeID,
+			UserID:    userID,
+			ExpiresAt: time.Now().UTC().Add(config.MFATokenTTL),
+		}); err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+
+		challenge, err := authentication.CreateMFAToken(userID, challengeID)
+		if err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+
+		responses.JSON(w, http.StatusOK, challenge)
+		return
+	}
+
+	a.startSession(w, r, userID)
+}
+
+func setLoginCookie(w http.ResponseWriter, name, path, value string, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(config.AppURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (a AuthController) startSession(w http.ResponseWriter, r *http.Request, userID uint64) {
+	sessionID, err := authentication.NewID()
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if err := a.sessionRepository.CreateSession(r.Context(), models.Session{
+		ID:        sessionID,
+		UserID:    userID,
+		UserAgent: r.UserAgent(),
+		IP:        requests.ClientIP(r),
+	}); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	tokens, err := a.issueTokens(r.Context(), userID, sessionID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+	a.metrics.LoginSucceeded()
+
+	responses.JSON(w, http.StatusOK, tokens)
+}
+
+func (a AuthController) issueTokens(ctx context.Context, userID uint64, sessionID string) (authentication.TokenPair, error) {
+	user, err := a.repository.GetUser(ctx, userID)
+	if err != nil {
+		return authentication.TokenPair{}, err
+	}
+
+	tokens, err := authentication.CreateToken(userID, user.Role, sessionID)
+	if err != nil {
+		return authentication.TokenPair{}, err
+	}
+
+	if err := a.tokenRepository.CreateRefreshToken(ctx, models.RefreshToken{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenHash: authentication.HashToken(tokens.RefreshToken),
+		ExpiresAt: tokens.RefreshExpiresAt,
+	}); err != nil {
+		return authentication.TokenPair{}, err
+	}
+
+	return tokens, nil
+}
+
+func (a AuthController) revokeReusedSession(w http.ResponseWriter, r *http.Request, token models.RefreshToken) {
+	if _, err := a.sessionRepository.RevokeSession(r.Context(), token.UserID, token.SessionID); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, token)
+	responses.Err(w, r, http.StatusUnauthorized, apperrors.New("auth.refresh_reused"))
 }
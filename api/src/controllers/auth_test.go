@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type refreshTest struct {
+	tokens     *fakeTokenRepository
+	sessions   *fakeSessionRepository
+	controller *AuthController
+	userID     uint64
+}
+
+func newRefreshTest(t *testing.T) (*refreshTest, authentication.TokenPair) {
+	users := newFakeUserRepository()
+	tokens := newFakeTokenRepository()
+	sessions := newFakeSessionRepository(tokens)
+
+	test := &refreshTest{
+		tokens:     tokens,
+		sessions:   sessions,
+		controller: newTestAuthController(users, tokens, sessions),
+		userID:     users.add(models.User{Name: "Ada", Nick: "ada", Email: "ada@example.com"}, true),
+	}
+
+	recorder := httptest.NewRecorder()
+	test.controller.startSession(recorder, httptest.NewRequest(http.MethodPost, "/login", nil), test.userID)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("startSession returned %d: %s", recorder.Code, recorder.Body)
+	}
+
+	return test, decodeTokens(t, recorder)
+}
+
+func (r *refreshTest) refresh(refreshToken string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(models.TokenRefresh{RefreshToken: refreshToken})
+	recorder := httptest.NewRecorder()
+	r.controller.RefreshToken(recorder, httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(string(body))))
+	return recorder
+}
+
+func decodeTokens(t *testing.T, recorder *httptest.ResponseRecorder) authentication.TokenPair {
+	t.Helper()
+
+	var tokens authentication.TokenPair
+	if err := json.NewDecoder(recorder.Body).Decode(&tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("response has no token pair")
+	}
+
+	return tokens
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	test, initial := newRefreshTest(t)
+
+	response := test.refresh(initial.RefreshToken)
+	if response.Code != http.StatusOK {
+		t.Fatalf("refresh returned %d: %s", response.Code, response.Body)
+	}
+
+	rotated := decodeTokens(t, response)
+	if rotated.RefreshToken == initial.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+
+	if response := test.refresh(rotated.RefreshToken); response.Code != http.StatusOK {
+		t.Fatalf("rotated token was rejected with %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	test, initial := newRefreshTest(t)
+
+	response := test.refresh(initial.RefreshToken)
+	if response.Code != http.StatusOK {
+		t.Fatalf("refresh returned %d: %s", response.Code, response.Body)
+	}
+	rotated := decodeTokens(t, response)
+
+	if response := test.refresh(initial.RefreshToken); response.Code != http.StatusUnauthorized {
+		t.Fatalf("reused token returned %d, want %d", response.Code, http.StatusUnauthorized)
+	}
+
+	sessions, _ := test.sessions.GetSessions(context.Background(), test.userID)
+	if len(sessions) != 1 || sessions[0].RevokedAt == nil {
+		t.Fatal("session was not revoked after token reuse")
+	}
+
+	if response := test.refresh(rotated.RefreshToken); response.Code != http.StatusUnauthorized {
+		t.Errorf("token rotated before the reuse was still accepted with %d", response.Code)
+	}
+}
+
+func TestRefreshTokenConcurrentUseRotatesOnce(t *testing.T) {
+	test, initial := newRefreshTest(t)
+
+	const attempts = 8
+	codes := make(chan int, attempts)
+
+	var wait sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			codes <- test.refresh(initial.RefreshToken).Code
+		}()
+	}
+	wait.Wait()
+	close(codes)
+
+	succeeded := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+		}
+	}
+
+	if succeeded != 1 {
+		t.Errorf("%d concurrent refreshes succeeded, want 1", succeeded)
+	}
+}
+
+func TestRefreshTokenRejectsUnknownToken(t *testing.T) {
+	test, _ := newRefreshTest(t)
+
+	if response := test.refresh("unknown-token"); response.Code != http.StatusUnauthorized {
+		t.Errorf("unknown token returned %d, want %d", response.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"api/src/apperrors"
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/metrics"
+	"api/src/models"
+	"api/src/repositories"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	config.SecretKey = []byte("test-secret-key")
+	config.AccessTokenTTL = 15 * time.Minute
+	config.RefreshTokenTTL = time.Hour
+	config.MFATokenTTL = 5 * time.Minute
+	config.OIDCStateTTL = 10 * time.Minute
+	config.OAuthCodeTTL = time.Minute
+	config.MagicLinkTTL = 15 * time.Minute
+	if err := authentication.LoadKeys(); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func newTestAuthController(users *fakeUserRepository, tokens *fakeTokenRepository, sessions *fakeSessionRepository) *AuthController {
+	return NewAuthController(
+		users,
+		tokens,
+		nil,
+		&fakeTwoFactorRepository{},
+		nil,
+		&fakeSecurityEventRepository{},
+		sessions,
+		nil,
+		nil,
+		metrics.New(nil),
+		testLogger,
+	)
+}
+
+type fakeUserRepository struct {
+	repositories.UserRepository
+
+	mutex    sync.Mutex
+	users    map[uint64]models.User
+	verified map[uint64]bool
+	nextID   uint64
+}
+
+func newFakeUserRepository() *fakeUserRepository {
+	return &fakeUserRepository{users: map[uint64]models.User{}, verified: map[uint64]bool{}}
+}
+
+func (f *fakeUserRepository) add(user models.User, verified bool) uint64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.nextID++
+	user.ID = f.nextID
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	f.users[user.ID] = user
+	f.verified[user.ID] = verified
+
+	return user.ID
+}
+
+func (f *fakeUserRepository) CreateUser(_ context.Context, user models.User) (uint64, error) {
+	return f.add(user, false), nil
+}
+
+func (f *fakeUserRepository) GetUser(_ context.Context, id uint64) (models.User, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	user, ok := f.users[id]
+	if !ok {
+		return models.User{}, apperrors.NotFound("user.not_found")
+	}
+	return user, nil
+}
+
+func (f *fakeUserRepository) GetUserByEmail(_ context.Context, email string) (models.User, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, apperrors.NotFound("user.not_found")
+}
+
+func (f *fakeUserRepository) ConfirmEmail(_ context.Context, userID uint64, _ string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.verified[userID] = true
+	return nil
+}
+
+func (f *fakeUserRepository) IsEmailVerified(_ context.Context, userID uint64) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.verified[userID], nil
+}
+
+type fakeTokenRepository struct {
+	mutex  sync.Mutex
+	tokens map[uint64]models.RefreshToken
+	nextID uint64
+}
+
+func newFakeTokenRepository() *fakeTokenRepository {
+	return &fakeTokenRepository{tokens: map[uint64]models.RefreshToken{}}
+}
+
+func (f *fakeTokenRepository) CreateRefreshToken(_ context.Context, token models.RefreshToken) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.nextID++
+	token.ID = f.nextID
+	f.tokens[token.ID] = token
+	return nil
+}
+
+func (f *fakeTokenRepository) GetRefreshToken(_ context.Context, tokenHash string) (models.RefreshToken, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, token := range f.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, nil
+}
+
+func (f *fakeTokenRepository) MarkRefreshTokenUsed(_ context.Context, id uint64) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	token, ok := f.tokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	f.tokens[id] = token
+	return true, nil
+}
+
+type fakeSessionRepository struct {
+	mutex    sync.Mutex
+	sessions map[string]models.Session
+	tokens   *fakeTokenRepository
+}
+
+func newFakeSessionRepository(tokens *fakeTokenRepository) *fakeSessionRepository {
+	return &fakeSessionRepository{sessions: map[string]models.Session{}, tokens: tokens}
+}
+
+func (f *fakeSessionRepository) CreateSession(_ context.Context, session models.Session) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.sessions[session.ID] = session
+	return nil
+}
+
+func (f *fakeSessionRepository) GetSession(_ context.Context, sessionID string) (models.Session, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	session, ok := f.sessions[sessionID]
+	if !ok {
+		return models.Session{}, apperrors.NotFound("session.not_found")
+	}
+	return session, nil
+}
+
+func (f *fakeSessionRepository) GetSessions(_ context.Context, userID uint64) ([]models.Session, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var sessions []models.Session
+	for _, session := range f.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (f *fakeSessionRepository) TouchSession(context.Context, string) error {
+	return nil
+}
+
+func (f *fakeSessionRepository) RevokeSession(_ context.Context, userID uint64, sessionID string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	session, ok := f.sessions[sessionID]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return false, nil
+	}
+
+	f.revoke(session)
+	return true, nil
+}
+
+func (f *fakeSessionRepository) RevokeOtherSessions(_ context.Context, userID uint64, currentSessionID string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, session := range f.sessions {
+		if session.UserID == userID && session.ID != currentSessionID && session.RevokedAt == nil {
+			f.revoke(session)
+		}
+	}
+	return nil
+}
+
+func (f *fakeSessionRepository) revoke(session models.Session) {
+	now := time.Now()
+	session.RevokedAt = &now
+	f.sessions[session.ID] = session
+
+	if f.tokens == nil {
+		return
+	}
+
+	f.tokens.mutex.Lock()
+	defer f.tokens.mutex.Unlock()
+
+	for id, token := range f.tokens.tokens {
+		if token.SessionID == session.ID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			f.tokens.tokens[id] = token
+		}
+	}
+}
+
+type fakeTwoFactorRepository struct {
+	repositories.TwoFactorRepository
+
+	mutex         sync.Mutex
+	twoFactor     models.TwoFactor
+	lastStep      int64
+	recoveryCodes map[string]bool
+}
+
+func (f *fakeTwoFactorRepository) GetTwoFactor(context.Context, uint64) (models.TwoFactor, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.twoFactor, nil
+}
+
+func (f *fakeTwoFactorRepository) Disable(context.Context, uint64) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.twoFactor = models.TwoFactor{}
+	return nil
+}
+
+func (f *fakeTwoFactorRepository) UseStep(_ context.Context, _ uint64, step int64) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if step <= f.lastStep {
+		return false, nil
+	}
+
+	f.lastStep = step
+	return true, nil
+}
+
+func (f *fakeTwoFactorRepository) UseRecoveryCode(_ context.Context, _ uint64, codeHash string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !f.recoveryCodes[codeHash] {
+		return false, nil
+	}
+
+	delete(f.recoveryCodes, codeHash)
+	return true, nil
+}
+
+type fakeLoginAttemptRepository struct {
+	mutex    sync.Mutex
+	failures map[string]int
+	blocked  map[string]time.Time
+}
+
+func newFakeLoginAttemptRepository() *fakeLoginAttemptRepository {
+	return &fakeLoginAttemptRepository{failures: map[string]int{}, blocked: map[string]time.Time{}}
+}
+
+func (f *fakeLoginAttemptRepository) GetBlockedUntil(_ context.Context, keys ...string) (time.Time, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var blockedUntil time.Time
+	for _, key := range keys {
+		if f.blocked[key].After(blockedUntil) {
+			blockedUntil = f.blocked[key]
+		}
+	}
+	return blockedUntil, nil
+}
+
+func (f *fakeLoginAttemptRepository) RecordFailure(_ context.Context, key string, _ time.Duration) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.failures[key]++
+	return f.failures[key], nil
+}
+
+func (f *fakeLoginAttemptRepository) Block(_ context.Context, key string, until time.Time) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.blocked[key] = until
+	return nil
+}
+
+func (f *fakeLoginAttemptRepository) Reset(_ context.Context, key string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.failures, key)
+	delete(f.blocked, key)
+	return nil
+}
+
+type fakeSecurityEventRepository struct {
+	mutex  sync.Mutex
+	events []models.SecurityEvent
+}
+
+func (f *fakeSecurityEventRepository) Record(_ context.Context, event models.SecurityEvent) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.events = append(f.events, event)
+	return nil
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"api/src/buildinfo"
+	"api/src/config"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+type HealthController struct {
+	repository       repositories.HealthRepository
+	migrationVersion uint
+	logger           *slog.Logger
+	draining         atomic.Bool
+}
+
+func NewHealthController(repository repositories.HealthRepository, migrationVersion uint, logger *slog.Logger) *HealthController {
+	return &HealthController{
+		repository:       repository,
+		migrationVersion: migrationVersion,
+		logger:           logger,
+	}
+}
+
+func (h *HealthController) Drain() {
+	h.draining.Store(true)
+}
+
+func (h *HealthController) Healthz(w http.ResponseWriter, r *http.Request) {
+	responses.JSON(w, http.StatusOK, models.HealthStatus{Status: "ok"})
+}
+
+func (h *HealthController) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), config.ReadinessTimeout)
+	defer cancel()
+
+	checks := map[string]string{
+		"server":     "ok",
+		"database":   "ok",
+		"migrations": "ok",
+	}
+	ready := true
+
+	if h.draining.Load() {
+		checks["server"] = "shutting down"
+		ready = false
+	}
+
+	if err := h.repository.Ping(ctx); err != nil {
+		h.logger.ErrorContext(r.Context(), "readiness: database ping failed", slog.Any("error", err))
+		checks["database"] = "unavailable"
+		ready = false
+	}
+
+	version, dirty, err := h.repository.MigrationVersion(ctx)
+	switch {
+	case err != nil:
+		h.logger.ErrorContext(r.Context(), "readiness: failed to read the migration version", slog.Any("error", err))
+		checks["migrations"] = "unavailable"
+		ready = false
+	case dirty:
+		h.logger.ErrorContext(r.Context(), "readiness: migration is dirty", slog.Uint64("version", uint64(version)))
+		checks["migrations"] = "dirty"
+		ready = false
+	case version != h.migrationVersion:
+		h.logger.ErrorContext(r.Context(), "readiness: unexpected migration version",
+			slog.Uint64("version", uint64(version)), slog.Uint64("expected", uint64(h.migrationVersion)))
+		checks["migrations"] = "outdated"
+		ready = false
+	}
+
+	if !ready {
+		h.logger.WarnContext(r.Context(), "readiness check failed", slog.Any("checks", checks))
+		responses.JSON(w, http.StatusServiceUnavailable, models.HealthStatus{Status: "unavailable", Checks: checks})
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, models.HealthStatus{Status: "ready", Checks: checks})
+}
+
+func (h *HealthController) Info(w http.ResponseWriter, r *http.Request) {
+	responses.JSON(w, http.StatusOK, models.BuildInfo{
+		Version:   buildinfo.Version,
+		Commit:    buildinfo.Commit,
+		StartedAt: buildinfo.StartedAt(),
+		Uptime:    buildinfo.Uptime().Round(time.Second).String(),
+	})
+}
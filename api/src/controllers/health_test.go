@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeHealthRepository struct {
+	pingErr    error
+	version    uint
+	dirty      bool
+	versionErr error
+}
+
+func (f *fakeHealthRepository) Ping(context.Context) error {
+	return f.pingErr
+}
+
+func (f *fakeHealthRepository) MigrationVersion(context.Context) (uint, bool, error) {
+	return f.version, f.dirty, f.versionErr
+}
+
+func TestReadyz(t *testing.T) {
+	cases := []struct {
+		name       string
+		repository *fakeHealthRepository
+		status     int
+		checks     map[string]string
+	}{
+		{"ready", &fakeHealthRepository{version: 18}, http.StatusOK, map[string]string{"database": "ok", "migrations": "ok"}},
+		{"database down", &fakeHealthRepository{pingErr: errors.New("dial tcp 10.0.0.5:5432: connection refused"), versionErr: errors.New("pq: password authentication failed for user devbook")}, http.StatusServiceUnavailable, map[string]string{"database": "unavailable", "migrations": "unavailable"}},
+		{"dirty", &fakeHealthRepository{version: 18, dirty: true}, http.StatusServiceUnavailable, map[string]string{"migrations": "dirty"}},
+		{"outdated", &fakeHealthRepository{version: 17}, http.StatusServiceUnavailable, map[string]string{"migrations": "outdated"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := NewHealthController(tc.repository, 18, testLogger)
+
+			recorder := httptest.NewRecorder()
+			controller.Readyz(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if recorder.Code != tc.status {
+				t.Fatalf("readyz returned %d, want %d: %s", recorder.Code, tc.status, recorder.Body)
+			}
+
+			for _, leaked := range []string{"10.0.0.5", "devbook"} {
+				if strings.Contains(recorder.Body.String(), leaked) {
+					t.Errorf("readyz leaked %q: %s", leaked, recorder.Body)
+				}
+			}
+
+			var body struct {
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+
+			for check, status := range tc.checks {
+				if body.Checks[check] != status {
+					t.Errorf("check %q = %q, want %q", check, body.Checks[check], status)
+				}
+			}
+		})
+	}
+}
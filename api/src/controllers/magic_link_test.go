@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeMagicLinkRepository struct {
+	mutex sync.Mutex
+	links map[string]models.MagicLink
+	used  map[string]bool
+}
+
+func (f *fakeMagicLinkRepository) CreateMagicLink(_ context.Context, link models.MagicLink) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.links[link.ID] = link
+	return nil
+}
+
+func (f *fakeMagicLinkRepository) ConsumeMagicLink(_ context.Context, id string, userID uint64, nonceHash string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	link, ok := f.links[id]
+	if !ok || link.UserID != userID || link.NonceHash != nonceHash || f.used[id] || !link.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+
+	f.used[id] = true
+	return true, nil
+}
+
+type magicLinkTest struct {
+	links      *fakeMagicLinkRepository
+	controller *AuthController
+	userID     uint64
+}
+
+func newMagicLinkTest() *magicLinkTest {
+	users := newFakeUserRepository()
+	tokens := newFakeTokenRepository()
+
+	test := &magicLinkTest{
+		links:      &fakeMagicLinkRepository{links: map[string]models.MagicLink{}, used: map[string]bool{}},
+		controller: newTestAuthController(users, tokens, newFakeSessionRepository(tokens)),
+		userID:     users.add(models.User{Name: "Ada", Nick: "ada", Email: "ada@example.com"}, true),
+	}
+	test.controller.magicLinkRepository = test.links
+
+	return test
+}
+
+func (m *magicLinkTest) issue(t *testing.T, expiresAt time.Time) (string, string) {
+	t.Helper()
+
+	linkID, err := authentication.NewID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, err := authentication.GenerateOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.links.CreateMagicLink(context.Background(), models.MagicLink{
+		ID:        linkID,
+		UserID:    m.userID,
+		NonceHash: authentication.HashToken(nonce),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := authentication.CreateMagicLinkToken(m.userID, linkID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token, nonce
+}
+
+func (m *magicLinkTest) verify(token, nonce string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(models.MagicLinkVerify{Token: token, Nonce: nonce})
+	request := httptest.NewRequest(http.MethodPost, "/login/magic/verify", strings.NewReader(string(body)))
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+
+	recorder := httptest.NewRecorder()
+	m.controller.VerifyMagicLink(recorder, request)
+	return recorder
+}
+
+func TestVerifyMagicLinkLogsInOnce(t *testing.T) {
+	test := newMagicLinkTest()
+	token, nonce := test.issue(t, time.Now().Add(config.MagicLinkTTL))
+
+	response := test.verify(token, nonce, nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("valid link returned %d: %s", response.Code, response.Body)
+	}
+
+	tokens := decodeTokens(t, response)
+	if principal, err := authentication.ParseToken(tokens.AccessToken); err != nil || principal.UserID != test.userID {
+		t.Errorf("access token belongs to user %d (%v), want %d", principal.UserID, err, test.userID)
+	}
+
+	if response := test.verify(token, nonce, nil); response.Code != http.StatusUnauthorized {
+		t.Errorf("reused link returned %d, want %d", response.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestVerifyMagicLinkReadsNonceFromCookie(t *testing.T) {
+	test := newMagicLinkTest()
+	token, nonce := test.issue(t, time.Now().Add(config.MagicLinkTTL))
+
+	if response := test.verify(token, "", &http.Cookie{Name: magicLinkCookie, Value: nonce}); response.Code != http.StatusOK {
+		t.Fatalf("link with the nonce cookie returned %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestVerifyMagicLinkRejectsInvalidLinks(t *testing.T) {
+	otherUser, err := authentication.CreateMagicLinkToken(99, "unknown-link")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		status int
+		token  func(t *testing.T, test *magicLinkTest) (string, string)
+	}{
+		{"missing nonce", http.StatusBadRequest, func(t *testing.T, test *magicLinkTest) (string, string) {
+			token, _ := test.issue(t, time.Now().Add(config.MagicLinkTTL))
+			return token, ""
+		}},
+		{"wrong nonce", http.StatusUnauthorized, func(t *testing.T, test *magicLinkTest) (string, string) {
+			token, _ := test.issue(t, time.Now().Add(config.MagicLinkTTL))
+			return token, "another-device"
+		}},
+		{"expired link", http.StatusUnauthorized, func(t *testing.T, test *magicLinkTest) (string, string) {
+			return test.issue(t, time.Now().Add(-time.Second))
+		}},
+		{"expired token", http.StatusUnauthorized, func(t *testing.T, test *magicLinkTest) (string, string) {
+			ttl := config.MagicLinkTTL
+			config.MagicLinkTTL = -time.Minute
+			defer func() { config.MagicLinkTTL = ttl }()
+
+			return test.issue(t, time.Now().Add(ttl))
+		}},
+		{"unknown link", http.StatusUnauthorized, func(t *testing.T, test *magicLinkTest) (string, string) {
+			_, nonce := test.issue(t, time.Now().Add(config.MagicLinkTTL))
+			return otherUser, nonce
+		}},
+		{"tampered token", http.StatusUnauthorized, func(t *testing.T, test *magicLinkTest) (string, string) {
+			token, nonce := test.issue(t, time.Now().Add(config.MagicLinkTTL))
+			return token[:len(token)-2] + "xx", nonce
+		}},
+		{"access token", http.StatusUnauthorized, func(t *testing.T, test *magicLinkTest) (string, string) {
+			_, nonce := test.issue(t, time.Now().Add(config.MagicLinkTTL))
+			tokens, err := authentication.CreateToken(test.userID, "user", "session-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tokens.AccessToken, nonce
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newMagicLinkTest()
+			token, nonce := tc.token(t, test)
+
+			if response := test.verify(token, nonce, nil); response.Code != tc.status {
+				t.Fatalf("verify returned %d, want %d: %s", response.Code, tc.status, response.Body)
+			}
+		})
+	}
+}
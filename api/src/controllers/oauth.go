@@ -0,0 +1,583 @@
+package controllers
+
+import (
+	"api/src/apperrors"
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/requests"
+	"api/src/responses"
+	"api/src/security"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type OAuthController struct {
+	repository        repositories.OAuthRepository
+	tokenRepository   repositories.TokenRepository
+	sessionRepository repositories.SessionRepository
+}
+
+func NewOAuthController(
+	repository repositories.OAuthRepository,
+	tokenRepository repositories.TokenRepository,
+	sessionRepository repositories.SessionRepository,
+) *OAuthController {
+	return &OAuthController{
+		repository:        repository,
+		tokenRepository:   tokenRepository,
+		sessionRepository: sessionRepository,
+	}
+}
+
+func (o *OAuthController) CreateClient(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var client models.OAuthClient
+	if err := json.Unmarshal(body, &client); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := client.Prepare(); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	for _, scope := range client.Scopes {
+		if !authentication.IsGrantableScope(scope) {
+			responses.Error(w, r, apperrors.Validation("oauth.scope_not_grantable", scope))
+			return
+		}
+	}
+
+	client.ID, err = authentication.NewID()
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if client.Confidential {
+		client.Secret, err = authentication.GenerateOpaqueToken()
+		if err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+		client.SecretHash = authentication.HashToken(client.Secret)
+	}
+
+	client.UserID = ID
+	client.CreatedAt = time.Now().UTC()
+
+	if err := o.repository.CreateClient(r.Context(), client); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusCreated, client)
+}
+
+func (o *OAuthController) GetClients(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	clients, err := o.repository.GetClients(r.Context(), ID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, clients)
+}
+
+func (o *OAuthController) RevokeClient(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	ID, err := strconv.ParseUint(params["id"], 10, 64)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	revoked, err := o.repository.RevokeClient(r.Context(), ID, params["clientId"])
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if !revoked {
+		responses.Error(w, r, apperrors.NotFound("oauth.client_not_found"))
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func (o *OAuthController) GetConsent(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	request := models.AuthorizationRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+
+	client, scopes, err := o.validateAuthorization(r.Context(), &request)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	consent := models.Consent{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		RedirectURI: request.RedirectURI,
+		State:       request.State,
+	}
+	for _, scope := range scopes {
+		consent.Scopes = append(consent.Scopes, models.ConsentScope{
+			Name:        scope,
+			Description: authentication.ScopeDescription(scope),
+		})
+	}
+
+	responses.JSON(w, http.StatusOK, consent)
+}
+
+func (o *OAuthController) Authorize(w http.ResponseWriter, r *http.Request) {
+	principal, err := authentication.PrincipalFromContext(r.Context())
+	if err != nil {
+		responses.Err(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var request models.AuthorizationRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	client, scopes, err := o.validateAuthorization(r.Context(), &request)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	params := url.Values{}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+
+	if !request.Approved {
+		params.Set("error", "access_denied")
+		responses.JSON(w, http.StatusOK, models.AuthorizationRedirect{RedirectURI: withQuery(request.RedirectURI, params)})
+		return
+	}
+
+	code, err := authentication.GenerateOpaqueToken()
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if err := o.repository.CreateAuthorizationCode(r.Context(), models.AuthorizationCode{
+		ClientID:      client.ID,
+		UserID:        principal.UserID,
+		CodeHash:      authentication.HashToken(code),
+		RedirectURI:   request.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: request.CodeChallenge,
+		ExpiresAt:     time.Now().UTC().Add(config.OAuthCodeTTL),
+	}); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	params.Set("code", code)
+	responses.JSON(w, http.StatusOK, models.AuthorizationRedirect{RedirectURI: withQuery(request.RedirectURI, params)})
+}
+
+func (o *OAuthController) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		oauthErr(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	client, ok := o.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		o.exchangeAuthorizationCode(w, r, client)
+	case "refresh_token":
+		o.exchangeRefreshToken(w, r, client)
+	default:
+		oauthErr(w, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+	}
+}
+
+func (o *OAuthController) Introspect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		oauthErr(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	client, ok := o.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	_, introspection, err := o.lookupToken(r.Context(), r.PostForm.Get("token"))
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if introspection.ClientID != client.ID {
+		introspection = models.TokenIntrospection{}
+	}
+
+	responses.JSON(w, http.StatusOK, introspection)
+}
+
+func (o *OAuthController) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		oauthErr(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	client, ok := o.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	session, _, err := o.lookupToken(r.Context(), r.PostForm.Get("token"))
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if session.ID != "" && session.ClientID == client.ID {
+		if _, err := o.sessionRepository.RevokeSession(r.Context(), session.UserID, session.ID); err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (o *OAuthController) Metadata(w http.ResponseWriter, r *http.Request) {
+	responses.JSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                config.AppURL,
+		"authorization_endpoint":                config.AppURL + "/oauth/authorize",
+		"token_endpoint":                        config.AppURL + "/oauth/token",
+		"introspection_endpoint":                config.AppURL + "/oauth/introspect",
+		"revocation_endpoint":                   config.AppURL + "/oauth/revoke",
+		"jwks_uri":                              config.AppURL + "/.well-known/jwks.json",
+		"scopes_supported":                      authentication.GrantableScopes,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+	})
+}
+
+func (o *OAuthController) validateAuthorization(ctx context.Context, request *models.AuthorizationRequest) (models.OAuthClient, []string, error) {
+	client, err := o.repository.GetClient(ctx, request.ClientID)
+	if err != nil {
+		return client, nil, err
+	}
+
+	if client.ID == "" || client.RevokedAt != nil {
+		return client, nil, apperrors.New("oauth.client_unknown")
+	}
+
+	if request.RedirectURI == "" && len(client.RedirectURIs) == 1 {
+		request.RedirectURI = client.RedirectURIs[0]
+	}
+
+	if !containsString(client.RedirectURIs, request.RedirectURI) {
+		return client, nil, apperrors.New("oauth.redirect_not_registered")
+	}
+
+	if request.ResponseType != "code" {
+		return client, nil, apperrors.New("oauth.response_type")
+	}
+
+	if request.CodeChallenge == "" || request.CodeChallengeMethod != "S256" {
+		return client, nil, apperrors.New("oauth.pkce_required")
+	}
+
+	scopes := strings.Fields(request.Scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+
+	for _, scope := range scopes {
+		if !containsString(client.Scopes, scope) || !authentication.IsGrantableScope(scope) {
+			return client, nil, apperrors.New("oauth.scope_not_allowed", scope)
+		}
+	}
+
+	return client, scopes, nil
+}
+
+func (o *OAuthController) authenticateClient(w http.ResponseWriter, r *http.Request) (models.OAuthClient, bool) {
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	client, err := o.repository.GetClient(r.Context(), clientID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return client, false
+	}
+
+	valid := client.ID != "" && client.RevokedAt == nil
+	if client.Confidential {
+		valid = valid && subtle.ConstantTimeCompare([]byte(authentication.HashToken(secret)), []byte(client.SecretHash)) == 1
+	} else {
+		valid = valid && secret == ""
+	}
+
+	if !valid {
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="devbook"`)
+		}
+		oauthErr(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return client, false
+	}
+
+	return client, true
+}
+
+func (o *OAuthController) exchangeAuthorizationCode(w http.ResponseWriter, r *http.Request, client models.OAuthClient) {
+	code, err := o.repository.ConsumeAuthorizationCode(r.Context(), authentication.HashToken(r.PostForm.Get("code")))
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if code.ID == 0 || code.ClientID != client.ID || code.RedirectURI != r.PostForm.Get("redirect_uri") {
+		oauthErr(w, http.StatusBadRequest, "invalid_grant", "authorization code is invalid or has expired")
+		return
+	}
+
+	challenge := security.PKCEChallenge(r.PostForm.Get("code_verifier"))
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+		oauthErr(w, http.StatusBadRequest, "invalid_grant", "code verifier does not match the code challenge")
+		return
+	}
+
+	sessionID, err := authentication.NewID()
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if err := o.sessionRepository.CreateSession(r.Context(), models.Session{
+		ID:        sessionID,
+		UserID:    code.UserID,
+		UserAgent: client.Name,
+		IP:        requests.ClientIP(r),
+		ClientID:  client.ID,
+		Scopes:    code.Scopes,
+	}); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	o.issueTokens(w, r, code.UserID, sessionID, client.ID, code.Scopes)
+}
+
+func (o *OAuthController) exchangeRefreshToken(w http.ResponseWriter, r *http.Request, client models.OAuthClient) {
+	savedToken, err := o.tokenRepository.GetRefreshToken(r.Context(), authentication.HashToken(r.PostForm.Get("refresh_token")))
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	session, err := o.sessionRepository.GetSession(r.Context(), savedToken.SessionID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if savedToken.ID == 0 || session.ClientID != client.ID || session.RevokedAt != nil {
+		oauthErr(w, http.StatusBadRequest, "invalid_grant", "refresh token is invalid")
+		return
+	}
+
+	if savedToken.UsedAt != nil || savedToken.RevokedAt != nil {
+		o.revokeReusedSession(w, r, session)
+		return
+	}
+
+	if time.Now().UTC().After(savedToken.ExpiresAt) {
+		oauthErr(w, http.StatusBadRequest, "invalid_grant", "refresh token has expired")
+		return
+	}
+
+	rotated, err := o.tokenRepository.MarkRefreshTokenUsed(r.Context(), savedToken.ID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if !rotated {
+		o.revokeReusedSession(w, r, session)
+		return
+	}
+
+	o.issueTokens(w, r, session.UserID, session.ID, client.ID, session.Scopes)
+}
+
+func (o *OAuthController) issueTokens(w http.ResponseWriter, r *http.Request, userID uint64, sessionID, clientID string, scopes []string) {
+	tokens, err := authentication.CreateClientToken(userID, sessionID, clientID, scopes)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if err := o.tokenRepository.CreateRefreshToken(r.Context(), models.RefreshToken{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenHash: authentication.HashToken(tokens.RefreshToken),
+		ExpiresAt: tokens.RefreshExpiresAt,
+	}); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, models.OAuthToken{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    tokens.TokenType,
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+		Scope:        strings.Join(scopes, " "),
+	})
+}
+
+func (o *OAuthController) revokeReusedSession(w http.ResponseWriter, r *http.Request, session models.Session) {
+	if _, err := o.sessionRepository.RevokeSession(r.Context(), session.UserID, session.ID); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	oauthErr(w, http.StatusBadRequest, "invalid_grant", "refresh token reuse detected, the grant was revoked")
+}
+
+func (o *OAuthController) lookupToken(ctx context.Context, token string) (models.Session, models.TokenIntrospection, error) {
+	if token == "" {
+		return models.Session{}, models.TokenIntrospection{}, nil
+	}
+
+	var sessionID, tokenType string
+	var expiresAt time.Time
+
+	if principal, err := authentication.ParseToken(token); err == nil {
+		sessionID, tokenType, expiresAt = principal.SessionID, "access_token", principal.ExpiresAt
+	} else {
+		savedToken, err := o.tokenRepository.GetRefreshToken(ctx, authentication.HashToken(token))
+		if err != nil {
+			return models.Session{}, models.TokenIntrospection{}, err
+		}
+
+		if savedToken.ID == 0 {
+			return models.Session{}, models.TokenIntrospection{}, nil
+		}
+
+		sessionID, tokenType, expiresAt = savedToken.SessionID, "refresh_token", savedToken.ExpiresAt
+		if savedToken.UsedAt != nil || savedToken.RevokedAt != nil {
+			expiresAt = time.Time{}
+		}
+	}
+
+	session, err := o.sessionRepository.GetSession(ctx, sessionID)
+	if err != nil || session.ID == "" {
+		return session, models.TokenIntrospection{}, err
+	}
+
+	if session.RevokedAt != nil || !time.Now().Before(expiresAt) {
+		return session, models.TokenIntrospection{}, nil
+	}
+
+	return session, models.TokenIntrospection{
+		Active:    true,
+		Scope:     strings.Join(session.Scopes, " "),
+		ClientID:  session.ClientID,
+		Subject:   strconv.FormatUint(session.UserID, 10),
+		TokenType: tokenType,
+		ExpiresAt: expiresAt.Unix(),
+	}, nil
+}
+
+func oauthErr(w http.ResponseWriter, statusCode int, code, description string) {
+	responses.JSON(w, statusCode, models.OAuthError{Error: code, ErrorDescription: description})
+}
+
+func withQuery(rawURL string, params url.Values) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := parsed.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
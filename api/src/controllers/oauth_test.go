@@ -0,0 +1,311 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/security"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const oauthRedirectURI = "https://client.example.com/callback"
+
+type fakeOAuthRepository struct {
+	mutex   sync.Mutex
+	clients map[string]models.OAuthClient
+	codes   map[string]models.AuthorizationCode
+	nextID  uint64
+}
+
+func newFakeOAuthRepository(clients ...models.OAuthClient) *fakeOAuthRepository {
+	repository := &fakeOAuthRepository{clients: map[string]models.OAuthClient{}, codes: map[string]models.AuthorizationCode{}}
+	for _, client := range clients {
+		repository.clients[client.ID] = client
+	}
+	return repository
+}
+
+func (f *fakeOAuthRepository) CreateClient(_ context.Context, client models.OAuthClient) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.clients[client.ID] = client
+	return nil
+}
+
+func (f *fakeOAuthRepository) GetClient(_ context.Context, clientID string) (models.OAuthClient, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.clients[clientID], nil
+}
+
+func (f *fakeOAuthRepository) GetClients(context.Context, uint64) ([]models.OAuthClient, error) {
+	return nil, nil
+}
+
+func (f *fakeOAuthRepository) RevokeClient(context.Context, uint64, string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeOAuthRepository) CreateAuthorizationCode(_ context.Context, code models.AuthorizationCode) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.nextID++
+	code.ID = f.nextID
+	f.codes[code.CodeHash] = code
+	return nil
+}
+
+func (f *fakeOAuthRepository) ConsumeAuthorizationCode(_ context.Context, codeHash string) (models.AuthorizationCode, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	code, ok := f.codes[codeHash]
+	delete(f.codes, codeHash)
+	if !ok || time.Now().After(code.ExpiresAt) {
+		return models.AuthorizationCode{}, nil
+	}
+	return code, nil
+}
+
+type oauthTest struct {
+	repository *fakeOAuthRepository
+	controller *OAuthController
+	verifier   string
+}
+
+func newOAuthTest(t *testing.T) *oauthTest {
+	verifier, err := security.GeneratePKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository := newFakeOAuthRepository(
+		models.OAuthClient{
+			ID:           "public-client",
+			RedirectURIs: []string{oauthRedirectURI},
+			Scopes:       []string{authentication.ScopePublicationsRead, authentication.ScopeUsersRead},
+		},
+		models.OAuthClient{
+			ID:           "confidential-client",
+			Confidential: true,
+			SecretHash:   authentication.HashToken("client-secret"),
+			RedirectURIs: []string{oauthRedirectURI},
+			Scopes:       []string{authentication.ScopePublicationsRead},
+		},
+	)
+
+	tokens := newFakeTokenRepository()
+	return &oauthTest{
+		repository: repository,
+		controller: NewOAuthController(repository, tokens, newFakeSessionRepository(tokens)),
+		verifier:   verifier,
+	}
+}
+
+func (o *oauthTest) authorize(t *testing.T, request models.AuthorizationRequest) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, _ := json.Marshal(request)
+	httpRequest := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(string(body)))
+	httpRequest = httpRequest.WithContext(authentication.WithPrincipal(httpRequest.Context(), authentication.Principal{UserID: 42}))
+
+	recorder := httptest.NewRecorder()
+	o.controller.Authorize(recorder, httpRequest)
+	return recorder
+}
+
+func (o *oauthTest) code(t *testing.T, clientID string) string {
+	t.Helper()
+
+	response := o.authorize(t, models.AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            clientID,
+		RedirectURI:         oauthRedirectURI,
+		Scope:               authentication.ScopePublicationsRead,
+		State:               "xyz",
+		CodeChallenge:       security.PKCEChallenge(o.verifier),
+		CodeChallengeMethod: "S256",
+		Approved:            true,
+	})
+	if response.Code != http.StatusOK {
+		t.Fatalf("authorize returned %d: %s", response.Code, response.Body)
+	}
+
+	var redirect models.AuthorizationRedirect
+	if err := json.NewDecoder(response.Body).Decode(&redirect); err != nil {
+		t.Fatal(err)
+	}
+
+	location, err := url.Parse(redirect.RedirectURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if location.Query().Get("state") != "xyz" || location.Query().Get("code") == "" {
+		t.Fatalf("redirect %s does not carry the state and code", redirect.RedirectURI)
+	}
+
+	return location.Query().Get("code")
+}
+
+func (o *oauthTest) exchange(form url.Values) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	recorder := httptest.NewRecorder()
+	o.controller.Token(recorder, request)
+	return recorder
+}
+
+func (o *oauthTest) codeForm(clientID, code string) url.Values {
+	return url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {clientID},
+		"code":          {code},
+		"redirect_uri":  {oauthRedirectURI},
+		"code_verifier": {o.verifier},
+	}
+}
+
+func oauthError(t *testing.T, response *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(response.Body).Decode(&body)
+	return body.Error
+}
+
+func TestOAuthCodeExchange(t *testing.T) {
+	test := newOAuthTest(t)
+
+	response := test.exchange(test.codeForm("public-client", test.code(t, "public-client")))
+	if response.Code != http.StatusOK {
+		t.Fatalf("exchange returned %d: %s", response.Code, response.Body)
+	}
+
+	if response.Header().Get("Cache-Control") != "no-store" {
+		t.Error("token response is cacheable")
+	}
+
+	var token models.OAuthToken
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken == "" || token.RefreshToken == "" || token.Scope != authentication.ScopePublicationsRead {
+		t.Errorf("unexpected token response %+v", token)
+	}
+
+	principal, err := authentication.ParseToken(token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if principal.UserID != 42 || principal.ClientID != "public-client" || !principal.HasScope(authentication.ScopePublicationsRead) || principal.HasScope(authentication.ScopeUsersRead) {
+		t.Errorf("unexpected access token principal %+v", principal)
+	}
+}
+
+func TestOAuthCodeExchangeRejectsInvalidGrants(t *testing.T) {
+	otherVerifier, err := security.GeneratePKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		tamper func(url.Values)
+	}{
+		{"wrong verifier", func(form url.Values) { form.Set("code_verifier", otherVerifier) }},
+		{"missing verifier", func(form url.Values) { form.Del("code_verifier") }},
+		{"challenge as verifier", func(form url.Values) { form.Set("code_verifier", security.PKCEChallenge(form.Get("code_verifier"))) }},
+		{"other redirect URI", func(form url.Values) { form.Set("redirect_uri", "https://attacker.example.com/callback") }},
+		{"unknown code", func(form url.Values) { form.Set("code", "unknown") }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newOAuthTest(t)
+			form := test.codeForm("public-client", test.code(t, "public-client"))
+			tc.tamper(form)
+
+			response := test.exchange(form)
+			if response.Code != http.StatusBadRequest || oauthError(t, response) != "invalid_grant" {
+				t.Fatalf("exchange returned %d: %s", response.Code, response.Body)
+			}
+		})
+	}
+}
+
+func TestOAuthCodeIsSingleUse(t *testing.T) {
+	test := newOAuthTest(t)
+	form := test.codeForm("public-client", test.code(t, "public-client"))
+
+	if response := test.exchange(form); response.Code != http.StatusOK {
+		t.Fatalf("first exchange returned %d: %s", response.Code, response.Body)
+	}
+
+	if response := test.exchange(form); response.Code != http.StatusBadRequest || oauthError(t, response) != "invalid_grant" {
+		t.Fatalf("replayed code returned %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestOAuthCodeIsBoundToTheClient(t *testing.T) {
+	test := newOAuthTest(t)
+	form := test.codeForm("confidential-client", test.code(t, "public-client"))
+	form.Set("client_secret", "client-secret")
+
+	if response := test.exchange(form); response.Code != http.StatusBadRequest || oauthError(t, response) != "invalid_grant" {
+		t.Fatalf("exchange by another client returned %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestOAuthConfidentialClientAuthentication(t *testing.T) {
+	test := newOAuthTest(t)
+
+	form := test.codeForm("confidential-client", test.code(t, "confidential-client"))
+	form.Set("client_secret", "wrong-secret")
+	if response := test.exchange(form); response.Code != http.StatusUnauthorized || oauthError(t, response) != "invalid_client" {
+		t.Fatalf("exchange with a wrong secret returned %d: %s", response.Code, response.Body)
+	}
+
+	form = test.codeForm("confidential-client", test.code(t, "confidential-client"))
+	form.Set("client_secret", "client-secret")
+	if response := test.exchange(form); response.Code != http.StatusOK {
+		t.Fatalf("exchange with the right secret returned %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestOAuthAuthorizeRequiresS256(t *testing.T) {
+	test := newOAuthTest(t)
+
+	for _, method := range []string{"", "plain"} {
+		response := test.authorize(t, models.AuthorizationRequest{
+			ResponseType:        "code",
+			ClientID:            "public-client",
+			RedirectURI:         oauthRedirectURI,
+			CodeChallenge:       test.verifier,
+			CodeChallengeMethod: method,
+			Approved:            true,
+		})
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("authorize with method %q returned %d", method, response.Code)
+		}
+	}
+
+	if len(test.repository.codes) != 0 {
+		t.Error("authorization code issued without an S256 challenge")
+	}
+}
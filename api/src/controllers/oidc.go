@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"api/src/apperrors"
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/logging"
+	"api/src/models"
+	"api/src/oidc"
+	"api/src/repositories"
+	"api/src/responses"
+	"api/src/security"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const oidcStateCookie = "devbook_oidc_state"
+
+var (
+	errInvalidLoginState      = apperrors.New("oidc.state_invalid")
+	errProviderFailed         = apperrors.New("oidc.provider_failed")
+	errUnverifiedProviderMail = apperrors.Forbidden("oidc.email_unverified")
+	errUnverifiedAccountMail  = apperrors.Conflict("oidc.account_unverified")
+
+	invalidNickCharacters = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+)
+
+type OIDCController struct {
+	providers          oidc.Providers
+	identityRepository repositories.IdentityRepository
+	userRepository     repositories.UserRepository
+	authController     *AuthController
+	logger             *slog.Logger
+}
+
+func NewOIDCController(
+	providers oidc.Providers,
+	identityRepository repositories.IdentityRepository,
+	userRepository repositories.UserRepository,
+	authController *AuthController,
+	logger *slog.Logger,
+) *OIDCController {
+	return &OIDCController{
+		providers:          providers,
+		identityRepository: identityRepository,
+		userRepository:     userRepository,
+		authController:     authController,
+		logger:             logger,
+	}
+}
+
+func (o *OIDCController) Begin(w http.ResponseWriter, r *http.Request) {
+	provider, err := o.providers.Get(mux.Vars(r)["provider"])
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	state, err := authentication.GenerateOpaqueToken()
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	verifier, err := security.GeneratePKCEVerifier()
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, verifier)
+	if err != nil {
+		o.providerFailed(w, r, err)
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(config.OIDCStateTTL)
+	if err := o.identityRepository.CreateLogin(r.Context(), models.OIDCLogin{
+		Provider:     provider.Name(),
+		StateHash:    authentication.HashToken(state),
+		CodeVerifier: verifier,
+		ExpiresAt:    expiresAt,
+	}); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	setLoginCookie(w, oidcStateCookie, "/login/oidc", state, expiresAt)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (o *OIDCController) Callback(w http.ResponseWriter, r *http.Request) {
+	provider, err := o.providers.Get(mux.Vars(r)["provider"])
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("oidc.denied"))
+		return
+	}
+
+	state, code := query.Get("state"), query.Get("code")
+	if state == "" || code == "" {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("oidc.state_required"))
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		responses.Err(w, r, http.StatusBadRequest, errInvalidLoginState)
+		return
+	}
+	setLoginCookie(w, oidcStateCookie, "/login/oidc", "", time.Unix(0, 0))
+
+	login, err := o.identityRepository.ConsumeLogin(r.Context(), provider.Name(), authentication.HashToken(state))
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if login.ID == 0 {
+		responses.Err(w, r, http.StatusBadRequest, errInvalidLoginState)
+		return
+	}
+
+	token, err := provider.Exchange(r.Context(), code, login.CodeVerifier)
+	if err != nil {
+		o.providerFailed(w, r, err)
+		return
+	}
+
+	identity, err := provider.Identity(r.Context(), token)
+	if err != nil {
+		o.providerFailed(w, r, err)
+		return
+	}
+
+	userID, err := o.resolveUser(r.Context(), identity)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	o.authController.completeLogin(w, r, userID)
+}
+
+func (o *OIDCController) providerFailed(w http.ResponseWriter, r *http.Request, err error) {
+	logging.FromContext(r.Context(), o.logger).Error("oidc: provider request failed", slog.Any("error", err))
+	responses.Err(w, r, http.StatusBadGateway, errProviderFailed)
+}
+
+func (o *OIDCController) resolveUser(ctx context.Context, identity oidc.Identity) (uint64, error) {
+	userID, err := o.identityRepository.GetUserIDByIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil || userID != 0 {
+		return userID, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return 0, errUnverifiedProviderMail
+	}
+
+	existing, err := o.userRepository.GetUserByEmail(ctx, identity.Email)
+	if err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+		return 0, err
+	}
+
+	if err == nil {
+		verified, err := o.userRepository.IsEmailVerified(ctx, existing.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		if !verified {
+			return 0, errUnverifiedAccountMail
+		}
+
+		userID = existing.ID
+	} else {
+		userID, err = o.provisionUser(ctx, identity)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := o.identityRepository.LinkIdentity(ctx, models.UserIdentity{
+		UserID:   userID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (o *OIDCController) provisionUser(ctx context.Context, identity oidc.Identity) (uint64, error) {
+	password, err := authentication.GenerateOpaqueToken()
+	if err != nil {
+		return 0, err
+	}
+
+	user := models.User{
+		Name:     identity.Name,
+		Nick:     nickFromIdentity(identity),
+		Email:    identity.Email,
+		Password: password,
+	}
+
+	if user.Name == "" {
+		user.Name = user.Nick
+	}
+
+	if err := user.Prepare("registration"); err != nil {
+		return 0, err
+	}
+
+	userID, err := o.userRepository.CreateUser(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := o.userRepository.ConfirmEmail(ctx, userID, user.Email); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func nickFromIdentity(identity oidc.Identity) string {
+	nick := identity.Username
+	if nick == "" {
+		nick, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	nick = strings.Trim(invalidNickCharacters.ReplaceAllString(nick, "_"), "_")
+	if len(nick) > 50 {
+		nick = nick[:50]
+	}
+
+	if nick == "" {
+		nick = identity.Provider + "_" + identity.Subject
+	}
+
+	return strings.ToLower(nick)
+}
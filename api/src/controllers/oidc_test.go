@@ -0,0 +1,466 @@
+package controllers
+
+import (
+	"api/src/apperrors"
+	"api/src/config"
+	"api/src/models"
+	"api/src/oidc"
+	"api/src/security"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+const (
+	fakeClientID = "devbook-client"
+	fakeKeyID    = "fake-key"
+)
+
+type fakeOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mutex     sync.Mutex
+	challenge string
+	claims    jwt.MapClaims
+	userInfo  map[string]interface{}
+	signer    *rsa.PrivateKey
+	keyID     string
+	noIDToken bool
+
+	jwksRequests int
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &fakeOIDCProvider{key: key, signer: key, keyID: fakeKeyID}
+
+	router := http.NewServeMux()
+	router.HandleFunc("/.well-known/openid-configuration", provider.discovery)
+	router.HandleFunc("/jwks", provider.jwks)
+	router.HandleFunc("/token", provider.token)
+	router.HandleFunc("/userinfo", provider.userinfo)
+
+	provider.server = httptest.NewServer(router)
+	t.Cleanup(provider.server.Close)
+
+	provider.setIdentity("subject-1", "ada@example.com", true)
+	return provider
+}
+
+func (f *fakeOIDCProvider) setIdentity(subject, email string, verified bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.claims = jwt.MapClaims{
+		"iss":            f.server.URL,
+		"aud":            fakeClientID,
+		"sub":            subject,
+		"email":          email,
+		"email_verified": verified,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+	f.userInfo = map[string]interface{}{
+		"sub":                subject,
+		"email":              email,
+		"email_verified":     verified,
+		"preferred_username": "ada",
+		"name":               "Ada Lovelace",
+	}
+}
+
+func (f *fakeOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 f.server.URL,
+		"authorization_endpoint": f.server.URL + "/authorize",
+		"token_endpoint":         f.server.URL + "/token",
+		"userinfo_endpoint":      f.server.URL + "/userinfo",
+		"jwks_uri":               f.server.URL + "/jwks",
+	})
+}
+
+func (f *fakeOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	f.jwksRequests++
+	f.mutex.Unlock()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": fakeKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (f *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != "valid-code" || r.PostForm.Get("client_id") != fakeClientID {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	if security.PKCEChallenge(r.PostForm.Get("code_verifier")) != f.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	response := map[string]string{"access_token": "access-token", "token_type": "Bearer"}
+	if !f.noIDToken {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, f.claims)
+		token.Header["kid"] = f.keyID
+		signed, err := token.SignedString(f.signer)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		response["id_token"] = signed
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeOIDCProvider) userinfo(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer access-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	json.NewEncoder(w).Encode(f.userInfo)
+}
+
+type fakeIdentityRepository struct {
+	mutex      sync.Mutex
+	logins     map[string]models.OIDCLogin
+	identities map[string]uint64
+	nextID     uint64
+}
+
+func newFakeIdentityRepository() *fakeIdentityRepository {
+	return &fakeIdentityRepository{logins: map[string]models.OIDCLogin{}, identities: map[string]uint64{}}
+}
+
+func (f *fakeIdentityRepository) CreateLogin(_ context.Context, login models.OIDCLogin) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.nextID++
+	login.ID = f.nextID
+	f.logins[login.StateHash] = login
+	return nil
+}
+
+func (f *fakeIdentityRepository) ConsumeLogin(_ context.Context, provider, stateHash string) (models.OIDCLogin, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	login, ok := f.logins[stateHash]
+	if !ok || login.Provider != provider {
+		return models.OIDCLogin{}, nil
+	}
+
+	delete(f.logins, stateHash)
+	return login, nil
+}
+
+func (f *fakeIdentityRepository) GetUserIDByIdentity(_ context.Context, provider, subject string) (uint64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.identities[provider+":"+subject], nil
+}
+
+func (f *fakeIdentityRepository) LinkIdentity(_ context.Context, identity models.UserIdentity) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.identities[identity.Provider+":"+identity.Subject] = identity.UserID
+	return nil
+}
+
+func (f *fakeIdentityRepository) tamperVerifiers(verifier string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for hash, login := range f.logins {
+		login.CodeVerifier = verifier
+		f.logins[hash] = login
+	}
+}
+
+type oidcTest struct {
+	provider   *fakeOIDCProvider
+	users      *fakeUserRepository
+	identities *fakeIdentityRepository
+	controller *OIDCController
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	provider := newFakeOIDCProvider(t)
+	users := newFakeUserRepository()
+	identities := newFakeIdentityRepository()
+	tokens := newFakeTokenRepository()
+
+	providers := oidc.Providers{
+		"fake": oidc.NewProvider(config.OIDCProvider{
+			Name:         "fake",
+			IssuerURL:    provider.server.URL,
+			ClientID:     fakeClientID,
+			ClientSecret: "secret",
+			RedirectURL:  "http://localhost/login/oidc/fake/callback",
+		}, provider.server.Client()),
+	}
+
+	return &oidcTest{
+		provider:   provider,
+		users:      users,
+		identities: identities,
+		controller: NewOIDCController(providers, identities, users, newTestAuthController(users, tokens, newFakeSessionRepository(tokens)), testLogger),
+	}
+}
+
+func (o *oidcTest) begin(t *testing.T) (string, *http.Cookie) {
+	request := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/login/oidc/fake", nil), map[string]string{"provider": "fake"})
+	recorder := httptest.NewRecorder()
+	o.controller.Begin(recorder, request)
+
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("begin returned %d: %s", recorder.Code, recorder.Body)
+	}
+
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := location.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL has no S256 code challenge: %s", location)
+	}
+
+	o.provider.mutex.Lock()
+	o.provider.challenge = query.Get("code_challenge")
+	o.provider.mutex.Unlock()
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != query.Get("state") {
+		t.Fatalf("state cookie does not match the authorization URL state")
+	}
+
+	return query.Get("state"), cookies[0]
+}
+
+func (o *oidcTest) callback(state, code string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	query := url.Values{"state": {state}, "code": {code}}
+	request := httptest.NewRequest(http.MethodGet, "/login/oidc/fake/callback?"+query.Encode(), nil)
+	request = mux.SetURLVars(request, map[string]string{"provider": "fake"})
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+
+	recorder := httptest.NewRecorder()
+	o.controller.Callback(recorder, request)
+	return recorder
+}
+
+func TestOIDCCallbackProvisionsVerifiedUser(t *testing.T) {
+	test := newOIDCTest(t)
+
+	state, cookie := test.begin(t)
+	response := test.callback(state, "valid-code", cookie)
+	if response.Code != http.StatusOK {
+		t.Fatalf("callback returned %d: %s", response.Code, response.Body)
+	}
+
+	user, err := test.users.GetUserByEmail(context.Background(), "ada@example.com")
+	if err != nil {
+		t.Fatalf("user was not provisioned: %v", err)
+	}
+
+	if verified, _ := test.users.IsEmailVerified(context.Background(), user.ID); !verified {
+		t.Error("provisioned user email should be verified")
+	}
+
+	if linked, _ := test.identities.GetUserIDByIdentity(context.Background(), "fake", "subject-1"); linked != user.ID {
+		t.Errorf("identity linked to %d, want %d", linked, user.ID)
+	}
+}
+
+func TestOIDCCallbackLinksExistingVerifiedAccount(t *testing.T) {
+	test := newOIDCTest(t)
+	userID := test.users.add(models.User{Name: "Ada", Nick: "ada", Email: "ada@example.com"}, true)
+
+	state, cookie := test.begin(t)
+	if response := test.callback(state, "valid-code", cookie); response.Code != http.StatusOK {
+		t.Fatalf("callback returned %d: %s", response.Code, response.Body)
+	}
+
+	if linked, _ := test.identities.GetUserIDByIdentity(context.Background(), "fake", "subject-1"); linked != userID {
+		t.Errorf("identity linked to %d, want existing user %d", linked, userID)
+	}
+}
+
+func TestOIDCCallbackRefusesToLinkUnverifiedEmails(t *testing.T) {
+	cases := []struct {
+		name             string
+		providerVerified bool
+		accountVerified  bool
+		status           int
+		err              error
+	}{
+		{"provider email unverified", false, true, http.StatusForbidden, errUnverifiedProviderMail},
+		{"account email unverified", true, false, http.StatusConflict, errUnverifiedAccountMail},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newOIDCTest(t)
+			test.users.add(models.User{Name: "Ada", Nick: "ada", Email: "ada@example.com"}, tc.accountVerified)
+			test.provider.setIdentity("subject-1", "ada@example.com", tc.providerVerified)
+
+			state, cookie := test.begin(t)
+			response := test.callback(state, "valid-code", cookie)
+			if response.Code != tc.status {
+				t.Fatalf("callback returned %d, want %d: %s", response.Code, tc.status, response.Body)
+			}
+
+			if linked, _ := test.identities.GetUserIDByIdentity(context.Background(), "fake", "subject-1"); linked != 0 {
+				t.Errorf("identity was linked to user %d", linked)
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackValidatesState(t *testing.T) {
+	test := newOIDCTest(t)
+	state, cookie := test.begin(t)
+
+	if response := test.callback(state, "valid-code", nil); response.Code != http.StatusBadRequest {
+		t.Errorf("callback without state cookie returned %d", response.Code)
+	}
+
+	forged := &http.Cookie{Name: cookie.Name, Value: "forged-state"}
+	if response := test.callback("forged-state", "valid-code", forged); response.Code != http.StatusBadRequest {
+		t.Errorf("callback with unknown state returned %d", response.Code)
+	}
+
+	if response := test.callback(state, "valid-code", cookie); response.Code != http.StatusOK {
+		t.Fatalf("callback returned %d: %s", response.Code, response.Body)
+	}
+
+	if response := test.callback(state, "valid-code", cookie); response.Code != http.StatusBadRequest {
+		t.Errorf("replayed state returned %d", response.Code)
+	}
+}
+
+func TestOIDCCallbackSendsPKCEVerifier(t *testing.T) {
+	test := newOIDCTest(t)
+	state, cookie := test.begin(t)
+
+	verifier, err := security.GeneratePKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.identities.tamperVerifiers(verifier)
+
+	if response := test.callback(state, "valid-code", cookie); response.Code != http.StatusBadGateway {
+		t.Fatalf("callback with a mismatched verifier returned %d: %s", response.Code, response.Body)
+	} else if strings.Contains(response.Body.String(), "PKCE verification failed") {
+		t.Errorf("callback leaked the provider error: %s", response.Body)
+	}
+}
+
+func TestOIDCCallbackVerifiesIDToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		tamper func(*fakeOIDCProvider)
+	}{
+		{"missing", func(f *fakeOIDCProvider) { f.noIDToken = true }},
+		{"wrong signature", func(f *fakeOIDCProvider) { f.signer = otherKey }},
+		{"wrong issuer", func(f *fakeOIDCProvider) { f.claims["iss"] = "https://attacker.example.com" }},
+		{"wrong audience", func(f *fakeOIDCProvider) { f.claims["aud"] = "another-client" }},
+		{"expired", func(f *fakeOIDCProvider) { f.claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"subject mismatch", func(f *fakeOIDCProvider) { f.claims["sub"] = "subject-2" }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newOIDCTest(t)
+			tc.tamper(test.provider)
+
+			state, cookie := test.begin(t)
+			if response := test.callback(state, "valid-code", cookie); response.Code != http.StatusBadGateway {
+				t.Fatalf("callback returned %d, want %d: %s", response.Code, http.StatusBadGateway, response.Body)
+			}
+
+			if _, err := test.users.GetUserByEmail(context.Background(), "ada@example.com"); err == nil {
+				t.Error("user was provisioned from an invalid ID token")
+			} else if !errors.Is(err, apperrors.ErrNotFound) {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestOIDCUnknownKeyIDDoesNotRefetchKeys(t *testing.T) {
+	test := newOIDCTest(t)
+	test.provider.keyID = "rotated-key"
+
+	for i := 0; i < 3; i++ {
+		state, cookie := test.begin(t)
+		if response := test.callback(state, "valid-code", cookie); response.Code != http.StatusBadGateway {
+			t.Fatalf("callback returned %d, want %d: %s", response.Code, http.StatusBadGateway, response.Body)
+		}
+	}
+
+	test.provider.mutex.Lock()
+	defer test.provider.mutex.Unlock()
+
+	if test.provider.jwksRequests != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", test.provider.jwksRequests)
+	}
+}
+
+func TestOIDCCallbackAcceptsAudienceList(t *testing.T) {
+	test := newOIDCTest(t)
+	test.provider.claims["aud"] = []string{"another-client", fakeClientID}
+
+	state, cookie := test.begin(t)
+	if response := test.callback(state, "valid-code", cookie); response.Code != http.StatusOK {
+		t.Fatalf("callback returned %d: %s", response.Code, response.Body)
+	}
+}
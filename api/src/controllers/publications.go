@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"api/src/apperrors"
 	"api/src/authentication"
+	"api/src/config"
+	"api/src/metrics"
 	"api/src/models"
 	"api/src/repositories"
+	"api/src/requests"
 	"api/src/responses"
 	"encoding/json"
-	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -15,75 +18,97 @@ import (
 )
 
 type PublicationController struct {
-	repository repositories.PublicationRepository
+	repository     repositories.PublicationRepository
+	userRepository repositories.UserRepository
+	metrics        *metrics.Metrics
 }
 
-func NewPublicationController(publicationRepository repositories.PublicationRepository) *PublicationController {
-	return &PublicationController{repository: publicationRepository}
+func NewPublicationController(publicationRepository repositories.PublicationRepository, userRepository repositories.UserRepository, metrics *metrics.Metrics) *PublicationController {
+	return &PublicationController{repository: publicationRepository, userRepository: userRepository, metrics: metrics}
 }
 
 func (p *PublicationController) CreatePublication(w http.ResponseWriter, r *http.Request) {
-	userID, err := authentication.ExtractUserID(r)
+	principal, err := authentication.PrincipalFromContext(r.Context())
 	if err != nil {
-		responses.Err(w, http.StatusUnauthorized, err)
+		responses.Err(w, r, http.StatusUnauthorized, err)
 		return
 	}
 
+	if config.RequireVerified {
+		verified, err := p.userRepository.IsEmailVerified(r.Context(), principal.UserID)
+		if err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+
+		if !verified {
+			responses.Error(w, r, apperrors.Forbidden("publication.unverified"))
+			return
+		}
+	}
+
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		responses.Err(w, http.StatusUnprocessableEntity, err)
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
 		return
 	}
 
 	var publication models.Publication
 	if err := json.Unmarshal(body, &publication); err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	publication.AuthorID = userID
+	publication.AuthorID = principal.UserID
 
 	if err := publication.Prepare(); err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Error(w, r, err)
 		return
 	}
 
-	publication.ID, err = p.repository.CreatePublication(publication)
+	publication.ID, err = p.repository.CreatePublication(r.Context(), publication)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
 		return
 	}
+	p.metrics.PublicationCreated()
 
 	responses.JSON(w, http.StatusCreated, publication)
 
 }
 func (p *PublicationController) GetPublications(w http.ResponseWriter, r *http.Request) {
-	userID, err := authentication.ExtractUserID(r)
+	principal, err := authentication.PrincipalFromContext(r.Context())
+	if err != nil {
+		responses.Err(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	page, err := requests.Page(r)
 	if err != nil {
-		responses.Err(w, http.StatusUnauthorized, err)
+		responses.Error(w, r, err)
 		return
 	}
 
-	publications, err := p.repository.GetPublications(userID)
+	publications, next, err := p.repository.GetPublications(r.Context(), principal.UserID, page)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, publications)
+	responses.Page(w, r, publications, next)
 }
 
 func (p *PublicationController) GetPublication(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	publicationID, err := strconv.ParseUint(params["publicationId"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	publication, err := p.repository.GetPublication(publicationID)
+	publication, err := p.repository.GetPublication(r.Context(), publicationID)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
 		return
 	}
 
@@ -92,49 +117,32 @@ func (p *PublicationController) GetPublication(w http.ResponseWriter, r *http.Re
 }
 
 func (p *PublicationController) UpdatePublication(w http.ResponseWriter, r *http.Request) {
-	userID, err := authentication.ExtractUserID(r)
-	if err != nil {
-		responses.Err(w, http.StatusUnauthorized, err)
-		return
-	}
-
 	params := mux.Vars(r)
 	publicationID, err := strconv.ParseUint(params["publicationId"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
-		return
-	}
-
-	savePublication, err := p.repository.GetPublication(publicationID)
-	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
-		return
-	}
-
-	if savePublication.AuthorID != userID {
-		responses.Err(w, http.StatusForbidden, errors.New("it is not possible to update a post that is not yours"))
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		responses.Err(w, http.StatusUnprocessableEntity, err)
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
 		return
 	}
 
 	var publication models.Publication
 	if err := json.Unmarshal(body, &publication); err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := publication.Prepare(); err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Error(w, r, err)
 		return
 	}
 
-	if err := p.repository.UpdatePublication(publicationID, publication); err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+	if err := p.repository.UpdatePublication(r.Context(), publicationID, publication); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
@@ -143,32 +151,15 @@ func (p *PublicationController) UpdatePublication(w http.ResponseWriter, r *http
 }
 
 func (p *PublicationController) DeletePublication(w http.ResponseWriter, r *http.Request) {
-	userID, err := authentication.ExtractUserID(r)
-	if err != nil {
-		responses.Err(w, http.StatusUnauthorized, err)
-		return
-	}
-
 	params := mux.Vars(r)
 	publicationID, err := strconv.ParseUint(params["publicationId"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	savePublication, err := p.repository.GetPublication(publicationID)
-	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
-		return
-	}
-
-	if savePublication.AuthorID != userID {
-		responses.Err(w, http.StatusForbidden, errors.New("it is not possible to delete a post that is not yours"))
-		return
-	}
-
-	if err := p.repository.DeletePublication(publicationID); err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+	if err := p.repository.DeletePublication(r.Context(), publicationID); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
@@ -180,16 +171,22 @@ func (p *PublicationController) SearchPublicationsByUser(w http.ResponseWriter,
 	params := mux.Vars(r)
 	userID, err := strconv.ParseUint(params["userId"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := requests.Page(r)
+	if err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
-	publications, err := p.repository.FindByUser(userID)
+	publications, next, err := p.repository.FindByUser(r.Context(), userID, page)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
 		return
 	}
-	responses.JSON(w, http.StatusOK, publications)
+	responses.Page(w, r, publications, next)
 
 }
 
@@ -197,25 +194,15 @@ func (p *PublicationController) LikePublication(w http.ResponseWriter, r *http.R
 	params := mux.Vars(r)
 	publicationID, err := strconv.ParseUint(params["publicationId"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	savePublication, err := p.repository.GetPublication(publicationID)
-	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
-		return
-	}
-
-	if savePublication.ID == 0 {
-		responses.Err(w, http.StatusNotFound, errors.New("publication not found"))
-		return
-	}
-
-	if err := p.repository.Like(publicationID); err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+	if err := p.repository.Like(r.Context(), publicationID); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
+	p.metrics.PublicationLiked()
 
 	responses.JSON(w, http.StatusNoContent, nil)
 
@@ -225,23 +212,12 @@ func (p *PublicationController) UnlikePublication(w http.ResponseWriter, r *http
 	params := mux.Vars(r)
 	publicationID, err := strconv.ParseUint(params["publicationId"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
-		return
-	}
-
-	savePublication, err := p.repository.GetPublication(publicationID)
-	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
-		return
-	}
-
-	if savePublication.ID == 0 {
-		responses.Err(w, http.StatusNotFound, errors.New("publication not found"))
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	if err := p.repository.Unlike(publicationID); err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+	if err := p.repository.Unlike(r.Context(), publicationID); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"api/src/apperrors"
+	"api/src/authentication"
+	"api/src/repositories"
+	"api/src/responses"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type SessionController struct {
+	repository repositories.SessionRepository
+}
+
+func NewSessionController(repository repositories.SessionRepository) *SessionController {
+	return &SessionController{repository: repository}
+}
+
+func (s *SessionController) GetSessions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	ID, err := strconv.ParseUint(params["id"], 10, 64)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	principal, err := authentication.PrincipalFromContext(r.Context())
+	if err != nil {
+		responses.Err(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	sessions, err := s.repository.GetSessions(r.Context(), ID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == principal.SessionID
+	}
+
+	responses.JSON(w, http.StatusOK, sessions)
+}
+
+func (s *SessionController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	ID, err := strconv.ParseUint(params["id"], 10, 64)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	revoked, err := s.repository.RevokeSession(r.Context(), ID, params["sessionId"])
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if !revoked {
+		responses.Error(w, r, apperrors.NotFound("session.not_found"))
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func (s *SessionController) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	ID, err := strconv.ParseUint(params["id"], 10, 64)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	principal, err := authentication.PrincipalFromContext(r.Context())
+	if err != nil {
+		responses.Err(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := s.repository.RevokeOtherSessions(r.Context(), ID, principal.SessionID); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
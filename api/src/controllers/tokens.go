@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"api/src/apperrors"
+	"api/src/authentication"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type TokenController struct {
+	repository repositories.PersonalAccessTokenRepository
+}
+
+func NewTokenController(repository repositories.PersonalAccessTokenRepository) *TokenController {
+	return &TokenController{repository: repository}
+}
+
+func (t *TokenController) CreateToken(w http.ResponseWriter, r *http.Request) {
+	ID, ok := t.pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var token models.PersonalAccessToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := token.Prepare(); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	for _, scope := range token.Scopes {
+		if !authentication.IsGrantableScope(scope) {
+			responses.Error(w, r, apperrors.Validation("token.scope_not_allowed", scope))
+			return
+		}
+	}
+
+	token.Token, err = authentication.GeneratePersonalAccessToken()
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	token.UserID = ID
+	token.TokenHash = authentication.HashToken(token.Token)
+	if token.ExpiresAt != nil {
+		expiresAt := token.ExpiresAt.UTC()
+		token.ExpiresAt = &expiresAt
+	}
+
+	token.ID, err = t.repository.CreateToken(r.Context(), token)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusCreated, token)
+}
+
+func (t *TokenController) GetTokens(w http.ResponseWriter, r *http.Request) {
+	ID, ok := t.pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := t.repository.GetTokens(r.Context(), ID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, tokens)
+}
+
+func (t *TokenController) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	ID, ok := t.pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	params := mux.Vars(r)
+	tokenID, err := strconv.ParseUint(params["tokenId"], 10, 64)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	revoked, err := t.repository.RevokeToken(r.Context(), ID, tokenID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if !revoked {
+		responses.Error(w, r, apperrors.NotFound("token.not_found"))
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func (t *TokenController) pathUserID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	params := mux.Vars(r)
+
+	ID, err := strconv.ParseUint(params["id"], 10, 64)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return 0, false
+	}
+
+	return ID, true
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type fakePersonalAccessTokenRepository struct {
+	created []models.PersonalAccessToken
+}
+
+func (f *fakePersonalAccessTokenRepository) CreateToken(_ context.Context, token models.PersonalAccessToken) (uint64, error) {
+	f.created = append(f.created, token)
+	return uint64(len(f.created)), nil
+}
+
+func (f *fakePersonalAccessTokenRepository) GetTokenByHash(_ context.Context, tokenHash string) (models.PersonalAccessToken, error) {
+	for _, token := range f.created {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return models.PersonalAccessToken{}, nil
+}
+
+func (f *fakePersonalAccessTokenRepository) GetTokens(context.Context, uint64) ([]models.PersonalAccessToken, error) {
+	return f.created, nil
+}
+
+func (f *fakePersonalAccessTokenRepository) RevokeToken(context.Context, uint64, uint64) (bool, error) {
+	return false, nil
+}
+
+func (f *fakePersonalAccessTokenRepository) TouchToken(context.Context, uint64) error {
+	return nil
+}
+
+func createPersonalAccessToken(controller *TokenController, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/users/7/tokens", strings.NewReader(body))
+	request = mux.SetURLVars(request, map[string]string{"id": "7"})
+
+	recorder := httptest.NewRecorder()
+	controller.CreateToken(recorder, request)
+	return recorder
+}
+
+func TestCreateTokenStoresOnlyTheHash(t *testing.T) {
+	repository := &fakePersonalAccessTokenRepository{}
+	controller := NewTokenController(repository)
+
+	response := createPersonalAccessToken(controller, `{"name":"ci","scopes":["publications:read"]}`)
+	if response.Code != http.StatusCreated {
+		t.Fatalf("create returned %d: %s", response.Code, response.Body)
+	}
+
+	var created models.PersonalAccessToken
+	if err := json.NewDecoder(response.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	if !authentication.IsPersonalAccessToken(created.Token) {
+		t.Fatalf("token %q does not carry the personal access token prefix", created.Token)
+	}
+
+	stored := repository.created[0]
+	if stored.UserID != 7 {
+		t.Errorf("token stored for user %d, want 7", stored.UserID)
+	}
+
+	if stored.TokenHash != authentication.HashToken(created.Token) {
+		t.Error("stored hash does not match the returned token")
+	}
+
+	if stored.TokenHash == created.Token || strings.Contains(stored.TokenHash, created.Token) {
+		t.Error("raw token leaked into the stored hash")
+	}
+}
+
+func TestCreateTokenRejectsUngrantableScopes(t *testing.T) {
+	for _, scope := range []string{authentication.ScopeAccount, "admin", ""} {
+		t.Run(scope, func(t *testing.T) {
+			repository := &fakePersonalAccessTokenRepository{}
+			controller := NewTokenController(repository)
+
+			body, _ := json.Marshal(map[string]interface{}{"name": "ci", "scopes": []string{authentication.ScopeUsersRead, scope}})
+			if response := createPersonalAccessToken(controller, string(body)); response.Code != http.StatusBadRequest {
+				t.Fatalf("create with scope %q returned %d: %s", scope, response.Code, response.Body)
+			}
+
+			if len(repository.created) != 0 {
+				t.Error("token with an ungrantable scope was stored")
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"api/src/apperrors"
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/requests"
+	"api/src/responses"
+	"api/src/security"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const recoveryCodeCount = 10
+
+type TwoFactorController struct {
+	repository     repositories.TwoFactorRepository
+	userRepository repositories.UserRepository
+	authController *AuthController
+}
+
+func NewTwoFactorController(repository repositories.TwoFactorRepository, userRepository repositories.UserRepository, authController *AuthController) *TwoFactorController {
+	return &TwoFactorController{repository: repository, userRepository: userRepository, authController: authController}
+}
+
+func (t *TwoFactorController) Setup(w http.ResponseWriter, r *http.Request) {
+	ID, ok := t.pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	twoFactor, err := t.repository.GetTwoFactor(r.Context(), ID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if twoFactor.EnabledAt != nil {
+		responses.Error(w, r, apperrors.Conflict("two_factor.already_enabled"))
+		return
+	}
+
+	user, err := t.userRepository.GetUser(r.Context(), ID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if err := t.repository.SetSecret(r.Context(), ID, secret); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, models.TwoFactorSetup{
+		Secret: secret,
+		URI:    security.TOTPURI(config.TOTPIssuer, user.Email, secret),
+	})
+}
+
+func (t *TwoFactorController) Confirm(w http.ResponseWriter, r *http.Request) {
+	ID, ok := t.pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var request models.TwoFactorCode
+	if !decodeTwoFactorBody(w, r, &request) {
+		return
+	}
+
+	twoFactor, err := t.repository.GetTwoFactor(r.Context(), ID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if twoFactor.EnabledAt != nil {
+		responses.Error(w, r, apperrors.Conflict("two_factor.already_enabled"))
+		return
+	}
+
+	if twoFactor.Secret == "" {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("two_factor.not_started"))
+		return
+	}
+
+	step, valid := security.ValidateTOTP(twoFactor.Secret, request.Code, time.Now())
+	if !valid {
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("two_factor.code_invalid"))
+		return
+	}
+
+	codes, err := security.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	codeHashes := make([]string, len(codes))
+	for i, code := range codes {
+		codeHashes[i] = authentication.HashToken(security.NormalizeRecoveryCode(code))
+	}
+
+	if err := t.repository.Enable(r.Context(), ID, step, codeHashes); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, models.RecoveryCodes{Codes: codes})
+}
+
+func (t *TwoFactorController) Disable(w http.ResponseWriter, r *http.Request) {
+	ID, ok := t.pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var request models.TwoFactorCode
+	if !decodeTwoFactorBody(w, r, &request) {
+		return
+	}
+
+	twoFactor, err := t.repository.GetTwoFactor(r.Context(), ID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if twoFactor.EnabledAt == nil {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("two_factor.not_enabled"))
+		return
+	}
+
+	mfaKey := mfaAttemptKey(ID)
+	if t.authController.rejectBlocked(w, r, mfaKey) {
+		return
+	}
+
+	valid, err := verifySecondFactor(r.Context(), t.repository, ID, twoFactor, request.Code, request.RecoveryCode)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if !valid {
+		if err := t.authController.recordLoginFailure(r.Context(), ID, requests.ClientIP(r), mfaKey, config.MFAMaxAttempts, true); err != nil {
+			responses.Error(w, r, err)
+			return
+		}
+
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("two_factor.code_invalid"))
+		return
+	}
+
+	if err := t.authController.loginAttemptRepository.Reset(r.Context(), mfaKey); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	if err := t.repository.Disable(r.Context(), ID); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func (t *TwoFactorController) pathUserID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	params := mux.Vars(r)
+
+	ID, err := strconv.ParseUint(params["id"], 10, 64)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return 0, false
+	}
+
+	return ID, true
+}
+
+func decodeTwoFactorBody(w http.ResponseWriter, r *http.Request, request interface{}) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return false
+	}
+
+	if err := json.Unmarshal(body, request); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return false
+	}
+
+	return true
+}
+
+func verifySecondFactor(ctx context.Context, repository repositories.TwoFactorRepository, userID uint64, twoFactor models.TwoFactor, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return repository.UseRecoveryCode(ctx, userID, authentication.HashToken(security.NormalizeRecoveryCode(recoveryCode)))
+	}
+
+	step, valid := security.ValidateTOTP(twoFactor.Secret, code, time.Now())
+	if !valid || step <= twoFactor.LastStep {
+		return false, nil
+	}
+
+	return repository.UseStep(ctx, userID, step)
+}
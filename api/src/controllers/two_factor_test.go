@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/models"
+	"api/src/security"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func currentTOTP(t *testing.T, secret string, offset int64) (string, int64) {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step := time.Now().Unix()/30 + offset
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	position := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[position:position+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%06d", value%1000000), step
+}
+
+func newTwoFactor(t *testing.T) models.TwoFactor {
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enabledAt := time.Now()
+	return models.TwoFactor{Secret: secret, EnabledAt: &enabledAt}
+}
+
+func TestVerifySecondFactorRejectsReplayedCode(t *testing.T) {
+	repository := &fakeTwoFactorRepository{}
+	twoFactor := newTwoFactor(t)
+	code, step := currentTOTP(t, twoFactor.Secret, 0)
+
+	if ok, err := verifySecondFactor(context.Background(), repository, 1, twoFactor, code, ""); err != nil || !ok {
+		t.Fatalf("fresh code rejected: %v", err)
+	}
+
+	if ok, _ := verifySecondFactor(context.Background(), repository, 1, twoFactor, code, ""); ok {
+		t.Fatal("replayed code accepted")
+	}
+
+	twoFactor.LastStep = step
+	if ok, _ := verifySecondFactor(context.Background(), repository, 1, twoFactor, code, ""); ok {
+		t.Fatal("code for the last used step accepted")
+	}
+}
+
+func TestVerifySecondFactorRejectsEarlierStepAfterLaterOne(t *testing.T) {
+	repository := &fakeTwoFactorRepository{}
+	twoFactor := newTwoFactor(t)
+
+	later, _ := currentTOTP(t, twoFactor.Secret, 1)
+	earlier, _ := currentTOTP(t, twoFactor.Secret, 0)
+	if later == earlier {
+		t.Skip("consecutive steps produced the same code")
+	}
+
+	if ok, err := verifySecondFactor(context.Background(), repository, 1, twoFactor, later, ""); err != nil || !ok {
+		t.Fatalf("code for the next step rejected: %v", err)
+	}
+
+	if ok, _ := verifySecondFactor(context.Background(), repository, 1, twoFactor, earlier, ""); ok {
+		t.Fatal("code for an earlier step accepted after a later one")
+	}
+}
+
+func TestVerifySecondFactorRecoveryCodesAreSingleUse(t *testing.T) {
+	codes, err := security.GenerateRecoveryCodes(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository := &fakeTwoFactorRepository{recoveryCodes: map[string]bool{}}
+	for _, code := range codes {
+		repository.recoveryCodes[authentication.HashToken(security.NormalizeRecoveryCode(code))] = true
+	}
+
+	twoFactor := newTwoFactor(t)
+	if ok, err := verifySecondFactor(context.Background(), repository, 1, twoFactor, "", codes[0]); err != nil || !ok {
+		t.Fatalf("recovery code rejected: %v", err)
+	}
+
+	if ok, _ := verifySecondFactor(context.Background(), repository, 1, twoFactor, "", codes[0]); ok {
+		t.Fatal("recovery code accepted twice")
+	}
+
+	if ok, _ := verifySecondFactor(context.Background(), repository, 1, twoFactor, "", " "+codes[1]+" "); !ok {
+		t.Fatal("unused recovery code rejected")
+	}
+}
+
+func TestDisableTwoFactorLocksOutAfterWrongCodes(t *testing.T) {
+	maxAttempts, lockout, backoffBase := config.MFAMaxAttempts, config.LoginLockout, config.LoginBackoffBase
+	config.MFAMaxAttempts, config.LoginLockout, config.LoginBackoffBase = 3, time.Minute, time.Nanosecond
+	t.Cleanup(func() {
+		config.MFAMaxAttempts, config.LoginLockout, config.LoginBackoffBase = maxAttempts, lockout, backoffBase
+	})
+
+	repository := &fakeTwoFactorRepository{twoFactor: newTwoFactor(t)}
+	attempts := newFakeLoginAttemptRepository()
+	events := &fakeSecurityEventRepository{}
+
+	auth := newTestAuthController(newFakeUserRepository(), newFakeTokenRepository(), nil)
+	auth.loginAttemptRepository = attempts
+	auth.securityEventRepository = events
+	controller := NewTwoFactorController(repository, nil, auth)
+
+	disable := func(code string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.TwoFactorCode{Code: code})
+		request := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/users/1/2fa/disable", bytes.NewReader(body)), map[string]string{"id": "1"})
+		recorder := httptest.NewRecorder()
+		controller.Disable(recorder, request)
+		return recorder
+	}
+
+	for i := 0; i < config.MFAMaxAttempts; i++ {
+		if response := disable("000000"); response.Code != http.StatusUnauthorized {
+			t.Fatalf("wrong code %d returned %d: %s", i+1, response.Code, response.Body)
+		}
+	}
+
+	code, _ := currentTOTP(t, repository.twoFactor.Secret, 0)
+	response := disable(code)
+	if response.Code != http.StatusTooManyRequests {
+		t.Fatalf("correct code after lockout returned %d, want %d", response.Code, http.StatusTooManyRequests)
+	}
+	if response.Header().Get("Retry-After") == "" {
+		t.Error("lockout response has no Retry-After header")
+	}
+
+	if repository.twoFactor.EnabledAt == nil {
+		t.Fatal("two-factor authentication was disabled while locked out")
+	}
+
+	if len(events.events) != 1 || events.events[0].Type != models.EventLoginLockout {
+		t.Errorf("recorded events = %+v, want one lockout", events.events)
+	}
+
+	attempts.Reset(context.Background(), mfaAttemptKey(1))
+	if response := disable(code); response.Code != http.StatusNoContent {
+		t.Fatalf("correct code returned %d: %s", response.Code, response.Body)
+	}
+
+	if repository.twoFactor.EnabledAt != nil {
+		t.Error("two-factor authentication is still enabled")
+	}
+}
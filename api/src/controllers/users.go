@@ -1,64 +1,100 @@
 package controllers
 
 import (
+	"api/src/apperrors"
 	"api/src/authentication"
+	"api/src/config"
+	"api/src/logging"
+	"api/src/mail"
+	"api/src/metrics"
 	"api/src/models"
 	"api/src/repositories"
+	"api/src/requests"
 	"api/src/responses"
 	"api/src/security"
+	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
 type UserController struct {
-	repository repositories.UserRepository
+	repository                  repositories.UserRepository
+	emailVerificationRepository repositories.EmailVerificationRepository
+	sessionRepository           repositories.SessionRepository
+	mailer                      mail.Sender
+	metrics                     *metrics.Metrics
+	logger                      *slog.Logger
 }
 
-func NewUserController(repository repositories.UserRepository) *UserController {
-	return &UserController{repository: repository}
+func NewUserController(
+	repository repositories.UserRepository,
+	emailVerificationRepository repositories.EmailVerificationRepository,
+	sessionRepository repositories.SessionRepository,
+	mailer mail.Sender,
+	metrics *metrics.Metrics,
+	logger *slog.Logger,
+) *UserController {
+	return &UserController{
+		repository:                  repository,
+		emailVerificationRepository: emailVerificationRepository,
+		sessionRepository:           sessionRepository,
+		mailer:                      mailer,
+		metrics:                     metrics,
+		logger:                      logger,
+	}
 }
 
 func (c UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		responses.Err(w, http.StatusUnprocessableEntity, err)
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
 		return
 	}
 
 	var user models.User
 	if err := json.Unmarshal(body, &user); err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := user.Prepare("registration"); err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Error(w, r, err)
 		return
 	}
 
-	user.ID, err = c.repository.CreateUser(user)
+	user.ID, err = c.repository.CreateUser(r.Context(), user)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
 		return
 	}
 
+	go c.sendEmailVerification(context.WithoutCancel(r.Context()), user.ID, user.Email)
+
 	responses.JSON(w, http.StatusCreated, user)
 }
 
 func (c UserController) GetUsers(w http.ResponseWriter, r *http.Request) {
+	page, err := requests.Page(r)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
 
-	users, err := c.repository.GetUsers()
+	users, next, err := c.repository.GetUsers(r.Context(), page)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, users)
+	responses.Page(w, r, users, next)
 
 }
 
@@ -67,13 +103,13 @@ func (c UserController) GetUser(w http.ResponseWriter, r *http.Request) {
 
 	ID, err := strconv.ParseUint(params["id"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	user, err := c.repository.GetUser(ID)
+	user, err := c.repository.GetUser(r.Context(), ID)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
 		return
 	}
 
@@ -86,41 +122,43 @@ func (u UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	ID, err := strconv.ParseUint(params["id"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	tokenUserID, err := authentication.ExtractUserID(r)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		responses.Err(w, http.StatusUnauthorized, err)
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
 		return
 	}
 
-	if ID != tokenUserID {
-		responses.Err(w, http.StatusForbidden, errors.New("you cannot update a user other than yourself"))
+	var user models.User
+	if err := json.Unmarshal(body, &user); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		responses.Err(w, http.StatusUnprocessableEntity, err)
+	if err := user.Prepare("update"); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
-	var user models.User
-	if err := json.Unmarshal(body, &user); err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+	saveUser, err := u.repository.GetUser(r.Context(), ID)
+	if err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
-	if err := user.Prepare("update"); err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+	newEmail := user.Email
+	user.Email = saveUser.Email
+
+	if err := u.repository.UpdateUser(r.Context(), ID, user); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
-	if err := u.repository.UpdateUser(ID, user); err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
-		return
+	if newEmail != saveUser.Email {
+		go u.sendEmailVerification(context.WithoutCancel(r.Context()), ID, newEmail)
 	}
 
 	responses.JSON(w, http.StatusNoContent, nil)
@@ -132,23 +170,12 @@ func (u UserController) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	ID, err := strconv.ParseUint(params["id"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
-		return
-	}
-
-	tokenUserID, err := authentication.ExtractUserID(r)
-	if err != nil {
-		responses.Err(w, http.StatusUnauthorized, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	if ID != tokenUserID {
-		responses.Err(w, http.StatusForbidden, errors.New("you cannot delete a user other than yourself"))
-		return
-	}
-
-	if err := u.repository.DeleteUser(ID); err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+	if err := u.repository.DeleteUser(r.Context(), ID); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
@@ -159,25 +186,26 @@ func (u *UserController) FollowUser(w http.ResponseWriter, r *http.Request) {
 
 	ID, err := strconv.ParseUint(params["id"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	followerID, err := authentication.ExtractUserID(r)
+	principal, err := authentication.PrincipalFromContext(r.Context())
 	if err != nil {
-		responses.Err(w, http.StatusUnauthorized, err)
+		responses.Err(w, r, http.StatusUnauthorized, err)
 		return
 	}
 
-	if ID == followerID {
-		responses.Err(w, http.StatusForbidden, errors.New("you cannot follow yourself"))
+	if ID == principal.UserID {
+		responses.Error(w, r, apperrors.Forbidden("user.follow_self"))
 		return
 	}
 
-	if err := u.repository.FollowUser(ID, followerID); err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+	if err := u.repository.FollowUser(r.Context(), ID, principal.UserID); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
+	u.metrics.UserFollowed()
 
 	responses.JSON(w, http.StatusNoContent, nil)
 }
@@ -187,23 +215,23 @@ func (u *UserController) UnfollowUser(w http.ResponseWriter, r *http.Request) {
 
 	ID, err := strconv.ParseUint(params["id"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	followerID, err := authentication.ExtractUserID(r)
+	principal, err := authentication.PrincipalFromContext(r.Context())
 	if err != nil {
-		responses.Err(w, http.StatusUnauthorized, err)
+		responses.Err(w, r, http.StatusUnauthorized, err)
 		return
 	}
 
-	if ID == followerID {
-		responses.Err(w, http.StatusForbidden, errors.New("you cannot unfollow yourself"))
+	if ID == principal.UserID {
+		responses.Error(w, r, apperrors.Forbidden("user.unfollow_self"))
 		return
 	}
 
-	if err := u.repository.UnfollowUser(ID, followerID); err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+	if err := u.repository.UnfollowUser(r.Context(), ID, principal.UserID); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
@@ -215,17 +243,23 @@ func (u *UserController) GetFollowers(w http.ResponseWriter, r *http.Request) {
 
 	ID, err := strconv.ParseUint(params["id"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	followers, err := u.repository.GetFollowers(ID)
+	page, err := requests.Page(r)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, followers)
+	followers, next, err := u.repository.GetFollowers(r.Context(), ID, page)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.Page(w, r, followers, next)
 }
 
 func (u *UserController) GetFollowing(w http.ResponseWriter, r *http.Request) {
@@ -233,17 +267,23 @@ func (u *UserController) GetFollowing(w http.ResponseWriter, r *http.Request) {
 
 	ID, err := strconv.ParseUint(params["id"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	followers, err := u.repository.GetFollowing(ID)
+	page, err := requests.Page(r)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, followers)
+	followers, next, err := u.repository.GetFollowing(r.Context(), ID, page)
+	if err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.Page(w, r, followers, next)
 }
 
 func (u *UserController) UpdatePassword(w http.ResponseWriter, r *http.Request) {
@@ -251,54 +291,164 @@ func (u *UserController) UpdatePassword(w http.ResponseWriter, r *http.Request)
 
 	ID, err := strconv.ParseUint(params["id"], 10, 64)
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var password models.Password
+	if err := json.Unmarshal(body, &password); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	tokenID, err := authentication.ExtractUserID(r)
+	storedPassword, err := u.repository.GetPassword(r.Context(), ID)
 	if err != nil {
-		responses.Err(w, http.StatusUnauthorized, err)
+		responses.Error(w, r, err)
 		return
 	}
 
-	if tokenID != ID {
-		responses.Err(w, http.StatusForbidden, errors.New("you cannot update the password other than yourself"))
+	if err := security.ValidatePassword(storedPassword, password.Current); err != nil {
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("user.password.wrong"))
+		return
+	}
+
+	if err := security.CheckPasswordPolicy(password.New); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	hashedPassword, err := security.Hash(password.New)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := u.repository.UpdatePassword(r.Context(), ID, string(hashedPassword)); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	principal, err := authentication.PrincipalFromContext(r.Context())
+	if err != nil {
+		responses.Err(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := u.sessionRepository.RevokeOtherSessions(r.Context(), ID, principal.SessionID); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func (u *UserController) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	ID, err := strconv.ParseUint(params["id"], 10, 64)
+	if err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		responses.Err(w, http.StatusUnprocessableEntity, err)
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
 		return
 	}
 
-	var password models.Password
-	if err := json.Unmarshal(body, &password); err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+	var role models.UserRole
+	if err := json.Unmarshal(body, &role); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if !authentication.IsValidRole(role.Role) {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("user.role.invalid"))
+		return
+	}
+
+	if err := u.repository.UpdateRole(r.Context(), ID, role.Role); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
-	storedPassword, err := u.repository.GetPassword(ID)
+	if err := u.sessionRepository.RevokeOtherSessions(r.Context(), ID, ""); err != nil {
+		responses.Error(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, nil)
+}
+
+func (u *UserController) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+		responses.Err(w, r, http.StatusUnprocessableEntity, err)
 		return
 	}
 
-	if err := security.ValidatePassword(storedPassword, password.Current); err != nil {
-		responses.Err(w, http.StatusUnauthorized, errors.New("password wrong"))
+	var verification models.EmailVerification
+	if err := json.Unmarshal(body, &verification); err != nil {
+		responses.Err(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	hashedPassword, err := security.Hash(password.New)
+	if verification.Token == "" {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("verification.required"))
+		return
+	}
+
+	userID, email, err := u.emailVerificationRepository.ConsumeEmailVerification(r.Context(), authentication.HashToken(verification.Token))
 	if err != nil {
-		responses.Err(w, http.StatusBadRequest, err)
+		responses.Error(w, r, err)
+		return
+	}
+
+	if userID == 0 {
+		responses.Err(w, r, http.StatusBadRequest, apperrors.New("verification.invalid"))
 		return
 	}
 
-	if err := u.repository.UpdatePassword(ID, string(hashedPassword)); err != nil {
-		responses.Err(w, http.StatusInternalServerError, err)
+	if err := u.repository.ConfirmEmail(r.Context(), userID, email); err != nil {
+		responses.Error(w, r, err)
 		return
 	}
 
 	responses.JSON(w, http.StatusNoContent, nil)
 }
+
+func (u *UserController) sendEmailVerification(ctx context.Context, userID uint64, email string) {
+	logger := logging.FromContext(ctx, u.logger)
+
+	token, err := authentication.GenerateOpaqueToken()
+	if err != nil {
+		logger.Error("email verification: failed to generate token", slog.Any("error", err))
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(config.EmailVerifyTTL)
+	if err := u.emailVerificationRepository.CreateEmailVerification(ctx, userID, email, authentication.HashToken(token), expiresAt); err != nil {
+		logger.Error("email verification: failed to store token", slog.Any("error", err))
+		return
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", config.AppURL, url.QueryEscape(token))
+	if err := u.mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Confirm your DevBook email address",
+		Body: fmt.Sprintf(
+			"Confirm this email address for your DevBook account by opening the link below within %s:\n\n%s\n\n"+
+				"If you did not request it, you can ignore this email.",
+			config.EmailVerifyTTL, link,
+		),
+	}); err != nil {
+		logger.Error("email verification: failed to send email", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,92 @@
+package i18n
+
+var en = map[string]string{
+	"name.required":       "name is required and cannot be blank",
+	"name.too_long":       "name cannot be longer than 100 characters",
+	"scopes.required":     "at least one scope is required",
+	"record.exists":       "%s already exists",
+	"record.missing":      "a referenced record does not exist",
+	"record.invalid":      "a value violates the %s constraint",
+	"email.in_use":        "email is already in use",
+	"request.invalid":     "the request is invalid",
+	"request.too_large":   "the request body cannot be larger than %d bytes",
+	"locale.unsupported":  "locale %q is not supported",
+	"page.cursor_invalid": "the cursor is invalid",
+	"page.limit_invalid":  "limit must be a positive integer",
+
+	"user.not_found":          "user not found",
+	"user.nick.required":      "nick is required and cannot be blank",
+	"user.email.required":     "email is required and cannot be blank",
+	"user.email.invalid":      "the provided email is invalid",
+	"user.password.required":  "password is required and cannot be blank",
+	"user.password.wrong":     "password wrong",
+	"user.role.invalid":       "role must be one of user, moderator or admin",
+	"user.follow_self":        "you cannot follow yourself",
+	"user.unfollow_self":      "you cannot unfollow yourself",
+	"password.too_short":      "password must be at least %d characters long",
+	"password.breached":       "this password has appeared in a data breach, choose a different one",
+	"verification.required":   "token is required",
+	"verification.invalid":    "verification token is invalid or has expired",
+	"publication.not_found":   "publication not found",
+	"publication.title":       "the title is required and cannot be empty",
+	"publication.content":     "the content is required and cannot be empty",
+	"publication.unverified":  "you must verify your email before posting",
+	"session.not_found":       "session not found",
+	"token.not_found":         "token not found",
+	"token.expires_in_past":   "expiration must be in the future",
+	"token.scope_not_allowed": "scope %q cannot be granted to a personal access token",
+
+	"auth.invalid_credentials":  "invalid email or password",
+	"auth.too_many_attempts":    "too many failed login attempts, try again later",
+	"auth.unauthenticated":      "request is not authenticated",
+	"auth.token_invalid":        "token is invalid",
+	"auth.token_expired":        "token has expired",
+	"auth.token_revoked":        "token has been revoked",
+	"auth.token_not_access":     "token is not an access token",
+	"auth.token_no_session":     "token has no session",
+	"auth.token_not_mfa":        "token is not an MFA challenge",
+	"auth.scope_missing":        "token does not grant the %s scope",
+	"auth.permission_denied":    "you do not have permission to perform this action",
+	"auth.refresh_required":     "refresh token is required",
+	"auth.refresh_invalid":      "refresh token is invalid",
+	"auth.refresh_expired":      "refresh token has expired",
+	"auth.refresh_oauth_client": "refresh token was issued to an OAuth client, use /oauth/token",
+	"auth.refresh_reused":       "refresh token reuse detected, the session was revoked",
+	"auth.reset_required":       "token and new password are required",
+	"auth.reset_invalid":        "reset token is invalid or has expired",
+	"auth.email_required":       "email is required",
+
+	"magic_link.too_many":       "too many sign-in links requested, try again later",
+	"magic_link.invalid":        "the sign-in link is invalid, has expired or was requested from another device",
+	"magic_link.required":       "token and device nonce are required",
+	"magic_link.not_magic_link": "token is not a magic link",
+	"magic_link.no_identifier":  "magic link has no identifier",
+
+	"rate_limit.exceeded":    "too many requests, try again later",
+	"rate_limit.unavailable": "rate limiting is unavailable, try again later",
+
+	"two_factor.already_enabled":   "two-factor authentication is already enabled",
+	"two_factor.not_started":       "two-factor setup has not been started",
+	"two_factor.not_enabled":       "two-factor authentication is not enabled",
+	"two_factor.code_invalid":      "the code is invalid",
+	"two_factor.challenge_invalid": "the two-factor challenge is invalid, has expired or was already used, sign in again",
+
+	"oidc.unknown_provider":   "unknown login provider",
+	"oidc.state_invalid":      "login state is invalid or has expired",
+	"oidc.state_required":     "state and code are required",
+	"oidc.denied":             "the provider denied the login request",
+	"oidc.email_unverified":   "the provider did not return a verified email address",
+	"oidc.account_unverified": "an account with this email already exists, sign in and verify its email before linking",
+	"oidc.provider_failed":    "login with the provider failed, try again later",
+
+	"oauth.client_not_found":        "client not found",
+	"oauth.client_unknown":          "client is unknown or has been revoked",
+	"oauth.redirect_required":       "at least one redirect URI is required",
+	"oauth.redirect_invalid":        "redirect URI %q must be an absolute URL without a fragment",
+	"oauth.redirect_insecure":       "redirect URI %q must use https unless it points to localhost",
+	"oauth.redirect_not_registered": "redirect URI is not registered for this client",
+	"oauth.response_type":           "response type must be code",
+	"oauth.pkce_required":           "a PKCE code challenge using S256 is required",
+	"oauth.scope_not_allowed":       "scope %q is not allowed for this client",
+	"oauth.scope_not_grantable":     "scope %q cannot be granted to an OAuth client",
+}
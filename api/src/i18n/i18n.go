@@ -0,0 +1,106 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultLocale = "en"
+
+var catalogs = map[string]map[string]string{
+	"en":    en,
+	"pt-BR": ptBR,
+}
+
+type localeContextKey struct{}
+
+type LocaleResolver func(ctx context.Context) string
+
+func WithLocaleResolver(ctx context.Context, resolver LocaleResolver) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, resolver)
+}
+
+func RequestLocale(r *http.Request) string {
+	if resolver, ok := r.Context().Value(localeContextKey{}).(LocaleResolver); ok {
+		if locale := resolver(r.Context()); locale != "" {
+			return locale
+		}
+	}
+	return Negotiate(r.Header.Get("Accept-Language"))
+}
+
+func Translate(locale, key string, args ...interface{}) string {
+	message, ok := catalogs[locale][key]
+	if !ok {
+		message, ok = catalogs[DefaultLocale][key]
+	}
+	if !ok {
+		message = key
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+func Supported(locale string) (string, bool) {
+	for name := range catalogs {
+		if strings.EqualFold(name, locale) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		tag     string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		if quality > 0 {
+			candidates = append(candidates, candidate{tag: tag, quality: quality})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, candidate := range candidates {
+		if locale, ok := Supported(candidate.tag); ok {
+			return locale
+		}
+
+		base, _, _ := strings.Cut(candidate.tag, "-")
+		for name := range catalogs {
+			nameBase, _, _ := strings.Cut(name, "-")
+			if strings.EqualFold(nameBase, base) {
+				return name
+			}
+		}
+	}
+
+	return DefaultLocale
+}
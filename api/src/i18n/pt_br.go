@@ -0,0 +1,92 @@
+package i18n
+
+var ptBR = map[string]string{
+	"name.required":       "o nome é obrigatório e não pode ficar em branco",
+	"name.too_long":       "o nome não pode ter mais de 100 caracteres",
+	"scopes.required":     "informe pelo menos um escopo",
+	"record.exists":       "%s já existe",
+	"record.missing":      "um registro referenciado não existe",
+	"record.invalid":      "um valor viola a restrição %s",
+	"email.in_use":        "este e-mail já está em uso",
+	"request.invalid":     "a requisição é inválida",
+	"request.too_large":   "o corpo da requisição não pode ter mais de %d bytes",
+	"locale.unsupported":  "o idioma %q não é suportado",
+	"page.cursor_invalid": "o cursor é inválido",
+	"page.limit_invalid":  "o limite deve ser um número inteiro positivo",
+
+	"user.not_found":          "usuário não encontrado",
+	"user.nick.required":      "o nick é obrigatório e não pode ficar em branco",
+	"user.email.required":     "o e-mail é obrigatório e não pode ficar em branco",
+	"user.email.invalid":      "o e-mail informado é inválido",
+	"user.password.required":  "a senha é obrigatória e não pode ficar em branco",
+	"user.password.wrong":     "senha incorreta",
+	"user.role.invalid":       "o papel deve ser user, moderator ou admin",
+	"user.follow_self":        "você não pode seguir a si mesmo",
+	"user.unfollow_self":      "você não pode deixar de seguir a si mesmo",
+	"password.too_short":      "a senha deve ter pelo menos %d caracteres",
+	"password.breached":       "esta senha apareceu em um vazamento de dados, escolha outra",
+	"verification.required":   "o token é obrigatório",
+	"verification.invalid":    "o token de verificação é inválido ou expirou",
+	"publication.not_found":   "publicação não encontrada",
+	"publication.title":       "o título é obrigatório e não pode ficar vazio",
+	"publication.content":     "o conteúdo é obrigatório e não pode ficar vazio",
+	"publication.unverified":  "você precisa verificar seu e-mail antes de publicar",
+	"session.not_found":       "sessão não encontrada",
+	"token.not_found":         "token não encontrado",
+	"token.expires_in_past":   "a expiração deve estar no futuro",
+	"token.scope_not_allowed": "o escopo %q não pode ser concedido a um token de acesso pessoal",
+
+	"auth.invalid_credentials":  "e-mail ou senha inválidos",
+	"auth.too_many_attempts":    "muitas tentativas de login malsucedidas, tente novamente mais tarde",
+	"auth.unauthenticated":      "a requisição não está autenticada",
+	"auth.token_invalid":        "o token é inválido",
+	"auth.token_expired":        "o token expirou",
+	"auth.token_revoked":        "o token foi revogado",
+	"auth.token_not_access":     "o token não é um token de acesso",
+	"auth.token_no_session":     "o token não possui sessão",
+	"auth.token_not_mfa":        "o token não é um desafio de segundo fator",
+	"auth.scope_missing":        "o token não concede o escopo %s",
+	"auth.permission_denied":    "você não tem permissão para realizar esta ação",
+	"auth.refresh_required":     "o refresh token é obrigatório",
+	"auth.refresh_invalid":      "o refresh token é inválido",
+	"auth.refresh_expired":      "o refresh token expirou",
+	"auth.refresh_oauth_client": "o refresh token foi emitido para um cliente OAuth, use /oauth/token",
+	"auth.refresh_reused":       "reutilização de refresh token detectada, a sessão foi revogada",
+	"auth.reset_required":       "o token e a nova senha são obrigatórios",
+	"auth.reset_invalid":        "o token de redefinição é inválido ou expirou",
+	"auth.email_required":       "o e-mail é obrigatório",
+
+	"magic_link.too_many":       "muitos links de acesso solicitados, tente novamente mais tarde",
+	"magic_link.invalid":        "o link de acesso é inválido, expirou ou foi solicitado em outro dispositivo",
+	"magic_link.required":       "o token e o nonce do dispositivo são obrigatórios",
+	"magic_link.not_magic_link": "o token não é um link de acesso",
+	"magic_link.no_identifier":  "o link de acesso não possui identificador",
+
+	"rate_limit.exceeded":    "muitas requisições, tente novamente mais tarde",
+	"rate_limit.unavailable": "o controle de requisições está indisponível, tente novamente mais tarde",
+
+	"two_factor.already_enabled":   "a autenticação em dois fatores já está ativada",
+	"two_factor.not_started":       "a configuração do segundo fator não foi iniciada",
+	"two_factor.not_enabled":       "a autenticação em dois fatores não está ativada",
+	"two_factor.code_invalid":      "o código é inválido",
+	"two_factor.challenge_invalid": "o desafio de dois fatores é inválido, expirou ou já foi usado, faça login novamente",
+
+	"oidc.unknown_provider":   "provedor de login desconhecido",
+	"oidc.state_invalid":      "o estado de login é inválido ou expirou",
+	"oidc.state_required":     "state e code são obrigatórios",
+	"oidc.denied":             "o provedor recusou a solicitação de login",
+	"oidc.email_unverified":   "o provedor não retornou um e-mail verificado",
+	"oidc.account_unverified": "já existe uma conta com este e-mail, entre e verifique o e-mail antes de vincular",
+	"oidc.provider_failed":    "não foi possível concluir o login com o provedor, tente novamente mais tarde",
+
+	"oauth.client_not_found":        "cliente não encontrado",
+	"oauth.client_unknown":          "o cliente é desconhecido ou foi revogado",
+	"oauth.redirect_required":       "informe pelo menos uma URI de redirecionamento",
+	"oauth.redirect_invalid":        "a URI de redirecionamento %q deve ser uma URL absoluta sem fragmento",
+	"oauth.redirect_insecure":       "a URI de redirecionamento %q deve usar https, exceto quando aponta para localhost",
+	"oauth.redirect_not_registered": "a URI de redirecionamento não está registrada para este cliente",
+	"oauth.response_type":           "o response type deve ser code",
+	"oauth.pkce_required":           "é obrigatório um desafio PKCE usando S256",
+	"oauth.scope_not_allowed":       "o escopo %q não é permitido para este cliente",
+	"oauth.scope_not_grantable":     "o escopo %q não pode ser concedido a um cliente OAuth",
+}
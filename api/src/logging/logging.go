@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type loggerContextKey struct{}
+
+type entryContextKey struct{}
+
+type Entry struct {
+	attrs []slog.Attr
+}
+
+func New(level, format string, w io.Writer) (*slog.Logger, error) {
+	var slogLevel slog.Level
+	if err := slogLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	options := &slog.HandlerOptions{Level: slogLevel}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expected json or text", format)
+	}
+}
+
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+func WithEntry(ctx context.Context) (context.Context, *Entry) {
+	entry := &Entry{}
+	return context.WithValue(ctx, entryContextKey{}, entry), entry
+}
+
+func (e *Entry) Attrs() []slog.Attr {
+	return e.attrs
+}
+
+func Annotate(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if entry, ok := ctx.Value(entryContextKey{}).(*Entry); ok {
+		entry.attrs = append(entry.attrs, attrs...)
+	}
+
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		args := make([]any, len(attrs))
+		for i, attr := range attrs {
+			args[i] = attr
+		}
+		ctx = WithLogger(ctx, logger.With(args...))
+	}
+
+	return ctx
+}
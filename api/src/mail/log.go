@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type logSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewLogSender(path string) Sender {
+	return &logSender{path: path}
+}
+
+func (l *logSender) Send(message Message) error {
+	entry := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", message.To, message.Subject, message.Body)
+
+	if l.path == "" {
+		log.Printf("\n%s", entry)
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "--- %s\n%s\n", time.Now().Format(time.RFC3339), entry)
+	return err
+}
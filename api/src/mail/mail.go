@@ -0,0 +1,27 @@
+package mail
+
+import (
+	"api/src/config"
+	"fmt"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(message Message) error
+}
+
+func NewSender() (Sender, error) {
+	switch config.MailDriver {
+	case "smtp":
+		return NewSMTPSender(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.MailFrom), nil
+	case "log", "":
+		return NewLogSender(config.MailLogPath), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", config.MailDriver)
+	}
+}
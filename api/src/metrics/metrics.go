@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "devbook"
+
+type Metrics struct {
+	registry            *prometheus.Registry
+	handler             http.Handler
+	requests            *prometheus.CounterVec
+	requestDuration     *prometheus.HistogramVec
+	requestsInFlight    *prometheus.GaugeVec
+	logins              *prometheus.CounterVec
+	publicationsCreated prometheus.Counter
+	follows             prometheus.Counter
+	likes               prometheus.Counter
+	rateLimitFailures   prometheus.Counter
+}
+
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by route template, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by route template and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		requestsInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served, by route template and method.",
+		}, []string{"route", "method"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts, by result.",
+		}, []string{"result"}),
+		publicationsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "publications_created_total",
+			Help:      "Publications created.",
+		}),
+		follows: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "follows_total",
+			Help:      "Users followed.",
+		}),
+		likes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "likes_total",
+			Help:      "Publications liked.",
+		}),
+		rateLimitFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_store_failures_total",
+			Help:      "Rate limit checks that failed because the store returned an error.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, namespace),
+		m.requests,
+		m.requestDuration,
+		m.requestsInFlight,
+		m.logins,
+		m.publicationsCreated,
+		m.follows,
+		m.likes,
+		m.rateLimitFailures,
+	)
+	m.handler = promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+
+	return m
+}
+
+func (m *Metrics) Handler(w http.ResponseWriter, r *http.Request) {
+	m.handler.ServeHTTP(w, r)
+}
+
+func (m *Metrics) RequestStarted(route, method string) func(status int) {
+	start := time.Now()
+	inFlight := m.requestsInFlight.WithLabelValues(route, method)
+	inFlight.Inc()
+
+	return func(status int) {
+		inFlight.Dec()
+		m.requests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+		m.requestDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *Metrics) LoginSucceeded() {
+	m.logins.WithLabelValues("succeeded").Inc()
+}
+
+func (m *Metrics) LoginFailed() {
+	m.logins.WithLabelValues("failed").Inc()
+}
+
+func (m *Metrics) PublicationCreated() {
+	m.publicationsCreated.Inc()
+}
+
+func (m *Metrics) UserFollowed() {
+	m.follows.Inc()
+}
+
+func (m *Metrics) PublicationLiked() {
+	m.likes.Inc()
+}
+
+func (m *Metrics) RateLimitStoreFailed() {
+	m.rateLimitFailures.Inc()
+}
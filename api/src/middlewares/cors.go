@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"api/src/config"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin != "" && len(config.CORSOrigins) > 0 {
+			switch {
+			case slices.Contains(config.CORSOrigins, origin):
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			case slices.Contains(config.CORSOrigins, "*"):
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			}
+
+			if w.Header().Get("Access-Control-Allow-Origin") != "" {
+				if config.CORSCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+				if len(config.CORSExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(config.CORSExposedHeaders, ", "))
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func Preflight(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.CORSMethods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(config.CORSHeaders, ", "))
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(config.CORSMaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,25 +1,422 @@
 package middlewares
 
 import (
+	"api/src/apperrors"
 	"api/src/authentication"
+	"api/src/config"
+	"api/src/i18n"
+	"api/src/logging"
+	"api/src/metrics"
+	"api/src/ratelimit"
+	"api/src/repositories"
+	"api/src/requests"
 	"api/src/responses"
-	"log"
+	"api/src/tracing"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func Logger(next http.HandlerFunc) http.HandlerFunc {
+type Ownership int
+
+const (
+	NoOwnership Ownership = iota
+	OwnedByPathUser
+	OwnedByPublicationAuthor
+)
+
+type Middlewares struct {
+	metrics                       *metrics.Metrics
+	logger                        *slog.Logger
+	userRepository                repositories.UserRepository
+	sessionRepository             repositories.SessionRepository
+	personalAccessTokenRepository repositories.PersonalAccessTokenRepository
+	publicationRepository         repositories.PublicationRepository
+	rateLimitStore                ratelimit.Store
+}
+
+func NewMiddlewares(
+	userRepository repositories.UserRepository,
+	sessionRepository repositories.SessionRepository,
+	personalAccessTokenRepository repositories.PersonalAccessTokenRepository,
+	publicationRepository repositories.PublicationRepository,
+	rateLimitStore ratelimit.Store,
+	metrics *metrics.Metrics,
+	logger *slog.Logger,
+) *Middlewares {
+	return &Middlewares{
+		metrics:                       metrics,
+		logger:                        logger,
+		userRepository:                userRepository,
+		sessionRepository:             sessionRepository,
+		personalAccessTokenRepository: personalAccessTokenRepository,
+		publicationRepository:         publicationRepository,
+		rateLimitStore:                rateLimitStore,
+	}
+}
+
+func (m *Middlewares) Logger(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		ctx, entry := logging.WithEntry(r.Context())
+		requestLogger := m.logger.With(slog.String("request_id", requests.ID(ctx)))
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			requestLogger = requestLogger.With(slog.String("trace_id", traceID), slog.String("span_id", tracing.SpanID(ctx)))
+		}
+		ctx = logging.WithLogger(ctx, requestLogger)
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		next(recorder, r.WithContext(ctx))
+
+		attrs := []slog.Attr{
+			slog.String("request_id", requests.ID(ctx)),
+			slog.String("trace_id", tracing.TraceID(ctx)),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", recorder.Status()),
+			slog.Int("bytes", recorder.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote_ip", requests.ClientIP(r)),
+			slog.String("user_agent", r.UserAgent()),
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				attrs = append(attrs, slog.String("route", template))
+			}
+		}
+		attrs = append(attrs, entry.Attrs()...)
+
+		level := slog.LevelInfo
+		if recorder.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		m.logger.LogAttrs(ctx, level, "request completed", attrs...)
+	}
+}
+
+func (m *Middlewares) Instrument(route, method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		done := m.metrics.RequestStarted(route, method)
+		recorder := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			done(recorder.Status())
+		}()
+
+		next(recorder, r)
+	}
+}
+
+func Trace(route, method string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("\n%s %s  %s\n", r.Method, r.RequestURI, r.Host)
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(requests.ClientIP(r)),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		next(recorder, r.WithContext(ctx))
+
+		status := recorder.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+func (m *Middlewares) RateLimit(route, method string, limit ratelimit.Limit, next http.HandlerFunc) http.HandlerFunc {
+	if m.rateLimitStore == nil || !limit.Enabled() {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := "ip:" + requests.ClientIP(r)
+		if principal, err := authentication.PrincipalFromContext(r.Context()); err == nil {
+			client = "user:" + strconv.FormatUint(principal.UserID, 10)
+		}
+
+		if m.takeRateLimit(w, r, method+" "+route+" "+client, limit) {
+			next(w, r)
+		}
+	}
+}
+
+func (m *Middlewares) RateLimitIP(limit ratelimit.Limit, next http.HandlerFunc) http.HandlerFunc {
+	if m.rateLimitStore == nil || !limit.Enabled() {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if m.takeRateLimit(w, r, "authenticated ip:"+requests.ClientIP(r), limit) {
+			next(w, r)
+		}
+	}
+}
+
+func (m *Middlewares) takeRateLimit(w http.ResponseWriter, r *http.Request, key string, limit ratelimit.Limit) bool {
+	result, err := m.rateLimitStore.Take(r.Context(), key, limit)
+	if err != nil {
+		m.metrics.RateLimitStoreFailed()
+		logging.FromContext(r.Context(), m.logger).ErrorContext(r.Context(), "rate limit check failed",
+			slog.Any("error", err), slog.String("on_error", config.RateLimitOnError))
+
+		if config.RateLimitOnError == "deny" {
+			responses.Err(w, r, http.StatusServiceUnavailable, apperrors.New("rate_limit.unavailable"))
+			return false
+		}
+		return true
+	}
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+	w.Header().Set("RateLimit-Policy", limit.String())
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+		responses.Err(w, r, http.StatusTooManyRequests, apperrors.New("rate_limit.exceeded"))
+		return false
+	}
+
+	return true
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func SecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("Content-Security-Policy", config.ContentSecurity)
+		if strings.HasPrefix(config.AppURL, "https://") {
+			w.Header().Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", int(config.HSTSMaxAge.Seconds())))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func LimitBody(maxBytes int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			responses.Err(w, r, http.StatusRequestEntityTooLarge, &http.MaxBytesError{Limit: maxBytes})
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 		next(w, r)
 	}
 }
 
-func Authenticate(next http.HandlerFunc) http.HandlerFunc {
+func RequestID(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := authentication.ValidateToken(r); err != nil {
-			responses.Err(w, http.StatusUnauthorized, err)
+		id := requests.IncomingID(r)
+		w.Header().Set(requests.RequestIDHeader, id)
+		next(w, r.WithContext(requests.WithID(r.Context(), id)))
+	}
+}
+
+func (m *Middlewares) Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenStr := authentication.ExtractToken(r)
+
+		var principal authentication.Principal
+		var ok bool
+		if authentication.IsPersonalAccessToken(tokenStr) {
+			principal, ok = m.authenticatePersonalAccessToken(w, r, tokenStr)
+		} else {
+			principal, ok = m.authenticateSession(w, r, tokenStr)
+		}
+
+		if !ok {
+			return
+		}
+
+		ctx := authentication.WithPrincipal(r.Context(), principal)
+		ctx = logging.Annotate(ctx, slog.Uint64("user_id", principal.UserID))
+		ctx = i18n.WithLocaleResolver(ctx, m.userLocale(principal.UserID))
+
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func (m *Middlewares) userLocale(userID uint64) i18n.LocaleResolver {
+	return func(ctx context.Context) string {
+		locale, err := m.userRepository.GetLocale(ctx, userID)
+		if err != nil {
+			logging.FromContext(ctx, m.logger).WarnContext(ctx, "failed to load user locale", slog.Any("error", err))
+			return ""
+		}
+		return locale
+	}
+}
+
+func (m *Middlewares) Authorize(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authentication.PrincipalFromContext(r.Context())
+		if err != nil {
+			responses.Err(w, r, http.StatusUnauthorized, err)
+			return
+		}
+
+		if scope != "" && !principal.HasScope(scope) {
+			responses.Error(w, r, apperrors.Forbidden("auth.scope_missing", scope))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (m *Middlewares) RequirePermission(permission authentication.Permission, ownership Ownership, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if permission == "" && ownership == NoOwnership {
+			next(w, r)
+			return
+		}
+
+		principal, err := authentication.PrincipalFromContext(r.Context())
+		if err != nil {
+			responses.Err(w, r, http.StatusUnauthorized, err)
+			return
+		}
+
+		if ownership != NoOwnership {
+			ownerID, ok := m.resolveOwner(w, r, ownership)
+			if !ok {
+				return
+			}
+
+			if ownerID == principal.UserID {
+				next(w, r)
+				return
+			}
+		}
+
+		if permission == "" || !principal.Can(permission) {
+			responses.Error(w, r, apperrors.Forbidden("auth.permission_denied"))
 			return
 		}
+
 		next(w, r)
 	}
 }
+
+func (m *Middlewares) resolveOwner(w http.ResponseWriter, r *http.Request, ownership Ownership) (uint64, bool) {
+	params := mux.Vars(r)
+
+	switch ownership {
+	case OwnedByPathUser:
+		userID, err := strconv.ParseUint(params["id"], 10, 64)
+		if err != nil {
+			responses.Err(w, r, http.StatusBadRequest, err)
+			return 0, false
+		}
+		return userID, true
+	case OwnedByPublicationAuthor:
+		publicationID, err := strconv.ParseUint(params["publicationId"], 10, 64)
+		if err != nil {
+			responses.Err(w, r, http.StatusBadRequest, err)
+			return 0, false
+		}
+
+		publication, err := m.publicationRepository.GetPublication(r.Context(), publicationID)
+		if err != nil {
+			responses.Error(w, r, err)
+			return 0, false
+		}
+		return publication.AuthorID, true
+	default:
+		responses.Err(w, r, http.StatusInternalServerError, fmt.Errorf("unknown ownership rule %d", ownership))
+		return 0, false
+	}
+}
+
+func (m *Middlewares) authenticateSession(w http.ResponseWriter, r *http.Request, tokenStr string) (authentication.Principal, bool) {
+	principal, err := authentication.ParseToken(tokenStr)
+	if err != nil {
+		responses.Err(w, r, http.StatusUnauthorized, err)
+		return principal, false
+	}
+
+	session, err := m.sessionRepository.GetSession(r.Context(), principal.SessionID)
+	if err != nil {
+		responses.Error(w, r, err)
+		return principal, false
+	}
+
+	if session.ID == "" || session.RevokedAt != nil || session.UserID != principal.UserID {
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("auth.token_revoked"))
+		return principal, false
+	}
+
+	if time.Since(session.LastSeenAt) > time.Minute {
+		if err := m.sessionRepository.TouchSession(r.Context(), session.ID); err != nil {
+			responses.Error(w, r, err)
+			return principal, false
+		}
+	}
+
+	return principal, true
+}
+
+func (m *Middlewares) authenticatePersonalAccessToken(w http.ResponseWriter, r *http.Request, tokenStr string) (authentication.Principal, bool) {
+	token, err := m.personalAccessTokenRepository.GetTokenByHash(r.Context(), authentication.HashToken(tokenStr))
+	if err != nil {
+		responses.Error(w, r, err)
+		return authentication.Principal{}, false
+	}
+
+	if token.ID == 0 || token.RevokedAt != nil {
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("auth.token_invalid"))
+		return authentication.Principal{}, false
+	}
+
+	if token.ExpiresAt != nil && time.Now().UTC().After(*token.ExpiresAt) {
+		responses.Err(w, r, http.StatusUnauthorized, apperrors.New("auth.token_expired"))
+		return authentication.Principal{}, false
+	}
+
+	if err := m.personalAccessTokenRepository.TouchToken(r.Context(), token.ID); err != nil {
+		responses.Error(w, r, err)
+		return authentication.Principal{}, false
+	}
+
+	principal := authentication.Principal{
+		UserID:  token.UserID,
+		TokenID: "pat_" + strconv.FormatUint(token.ID, 10),
+		Scopes:  token.Scopes,
+	}
+	if token.ExpiresAt != nil {
+		principal.ExpiresAt = *token.ExpiresAt
+	}
+
+	return principal, true
+}
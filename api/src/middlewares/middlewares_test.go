@@ -0,0 +1,165 @@
+package middlewares
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/metrics"
+	"api/src/models"
+	"api/src/ratelimit"
+	"api/src/repositories"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+type fakeUserRepository struct {
+	repositories.UserRepository
+}
+
+func (f fakeUserRepository) GetLocale(context.Context, uint64) (string, error) {
+	return "", nil
+}
+
+type fakePersonalAccessTokenRepository struct {
+	repositories.PersonalAccessTokenRepository
+
+	tokens  map[string]models.PersonalAccessToken
+	touched []uint64
+}
+
+func (f *fakePersonalAccessTokenRepository) GetTokenByHash(_ context.Context, tokenHash string) (models.PersonalAccessToken, error) {
+	return f.tokens[tokenHash], nil
+}
+
+func (f *fakePersonalAccessTokenRepository) TouchToken(_ context.Context, tokenID uint64) error {
+	f.touched = append(f.touched, tokenID)
+	return nil
+}
+
+func TestPersonalAccessTokenAuthentication(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tokens := map[string]models.PersonalAccessToken{}
+	issue := func(token models.PersonalAccessToken) string {
+		raw, err := authentication.GeneratePersonalAccessToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		token.ID = uint64(len(tokens) + 1)
+		token.UserID = 42
+		tokens[authentication.HashToken(raw)] = token
+		return raw
+	}
+
+	reader := issue(models.PersonalAccessToken{Scopes: []string{authentication.ScopePublicationsRead}, ExpiresAt: &future})
+	writer := issue(models.PersonalAccessToken{Scopes: []string{authentication.ScopePublicationsWrite}})
+	revoked := issue(models.PersonalAccessToken{Scopes: []string{authentication.ScopePublicationsRead}, RevokedAt: &past})
+	expired := issue(models.PersonalAccessToken{Scopes: []string{authentication.ScopePublicationsRead}, ExpiresAt: &past})
+
+	repository := &fakePersonalAccessTokenRepository{tokens: tokens}
+	m := NewMiddlewares(fakeUserRepository{}, nil, repository, nil, nil, nil, testLogger)
+
+	var principal authentication.Principal
+	handler := m.Authenticate(m.Authorize(authentication.ScopePublicationsRead, func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = authentication.PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"granted scope", reader, http.StatusNoContent},
+		{"missing scope", writer, http.StatusForbidden},
+		{"revoked", revoked, http.StatusUnauthorized},
+		{"expired", expired, http.StatusUnauthorized},
+		{"unknown", authentication.PersonalAccessTokenPrefix + "unknown", http.StatusUnauthorized},
+		{"hash presented as token", authentication.HashToken(reader), http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			principal = authentication.Principal{}
+
+			request := httptest.NewRequest(http.MethodGet, "/publications", nil)
+			request.Header.Set("Authorization", "Bearer "+tc.token)
+			recorder := httptest.NewRecorder()
+			handler(recorder, request)
+
+			if recorder.Code != tc.status {
+				t.Fatalf("got status %d, want %d: %s", recorder.Code, tc.status, recorder.Body)
+			}
+
+			if tc.status == http.StatusNoContent && (principal.UserID != 42 || principal.Role != "" || principal.HasScope(authentication.ScopeAccount)) {
+				t.Errorf("unexpected principal %+v", principal)
+			}
+		})
+	}
+
+	if len(repository.touched) != 2 {
+		t.Errorf("touched %d tokens, want only the 2 valid ones", len(repository.touched))
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func TestRateLimitIPRunsBeforeAuthentication(t *testing.T) {
+	repository := &fakePersonalAccessTokenRepository{tokens: map[string]models.PersonalAccessToken{}}
+	m := NewMiddlewares(fakeUserRepository{}, nil, repository, nil, ratelimit.NewMemoryStore(), metrics.New(nil), testLogger)
+
+	handler := m.RateLimitIP(ratelimit.Limit{Requests: 2, Window: time.Minute}, m.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	statuses := make([]int, 3)
+	for i := range statuses {
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		request.Header.Set("Authorization", "Bearer "+authentication.PersonalAccessTokenPrefix+"guess")
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		statuses[i] = recorder.Code
+	}
+
+	if statuses[0] != http.StatusUnauthorized || statuses[1] != http.StatusUnauthorized || statuses[2] != http.StatusTooManyRequests {
+		t.Errorf("token guesses returned %v, want two 401s then 429", statuses)
+	}
+}
+
+func TestRateLimitStoreFailures(t *testing.T) {
+	defer func(previous string) { config.RateLimitOnError = previous }(config.RateLimitOnError)
+
+	m := NewMiddlewares(fakeUserRepository{}, nil, nil, nil, failingStore{}, metrics.New(nil), testLogger)
+	handler := m.RateLimit("/users", http.MethodGet, ratelimit.Limit{Requests: 1, Window: time.Minute}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cases := map[string]int{
+		"allow": http.StatusNoContent,
+		"deny":  http.StatusServiceUnavailable,
+	}
+
+	for policy, status := range cases {
+		t.Run(policy, func(t *testing.T) {
+			config.RateLimitOnError = policy
+
+			recorder := httptest.NewRecorder()
+			handler(recorder, httptest.NewRequest(http.MethodGet, "/users", nil))
+			if recorder.Code != status {
+				t.Errorf("got status %d, want %d", recorder.Code, status)
+			}
+		})
+	}
+}
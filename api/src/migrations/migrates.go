@@ -2,25 +2,66 @@ package migrations
 
 import (
 	"api/src/config"
+	"embed"
+	"errors"
+	"io/fs"
 	"log"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
+//go:embed sql/*.sql
+var files embed.FS
+
 func RunMigrations() error {
-	m, err := migrate.New("file://src/migrations/sql", config.DBConnectionString)
+	source, err := iofs.New(files, "sql")
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, config.DBConnectionString)
 	if err != nil {
 		return err
 	}
+	defer m.Close()
 
 	err = m.Up()
 	if err != nil && err != migrate.ErrNoChange {
 		return err
 	}
 
-	log.Println("Migrations executed successfully")
+	version, _, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Migrations executed successfully, schema at version %d\n", version)
 
 	return nil
 }
+
+func LatestVersion() (uint, error) {
+	source, err := iofs.New(files, "sql")
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLatestVersionMatchesEmbeddedFiles(t *testing.T) {
+	names, err := fs.Glob(files, "sql/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected uint64
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(strings.TrimPrefix(name, "sql/"), "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version > expected {
+			expected = version
+		}
+	}
+
+	version, err := LatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected == 0 || uint64(version) != expected {
+		t.Errorf("LatestVersion() = %d, want %d", version, expected)
+	}
+}
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+UPDATE users SET email_verified_at = created_at;
+
+CREATE TABLE email_verifications (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
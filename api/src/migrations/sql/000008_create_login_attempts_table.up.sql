@@ -0,0 +1,19 @@
+CREATE TABLE login_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    blocked_until TIMESTAMP
+);
+
+CREATE TABLE security_events (
+    id SERIAL PRIMARY KEY,
+    user_id INT,
+    type VARCHAR(64) NOT NULL,
+    ip VARCHAR(64),
+    detail TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE SET NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user',
+    ADD CONSTRAINT chk_users_role CHECK (role IN ('user', 'moderator', 'admin'));
@@ -0,0 +1,7 @@
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_session;
+
+ALTER INDEX idx_refresh_tokens_session_id RENAME TO idx_refresh_tokens_family_id;
+
+ALTER TABLE refresh_tokens RENAME COLUMN session_id TO family_id;
+
+DROP TABLE IF EXISTS sessions;
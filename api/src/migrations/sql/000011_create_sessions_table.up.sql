@@ -0,0 +1,30 @@
+CREATE TABLE sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL,
+    user_agent TEXT,
+    ip VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP,
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+INSERT INTO sessions (id, user_id, created_at, last_seen_at, revoked_at)
+SELECT family_id, user_id, MIN(created_at), MAX(created_at), MAX(revoked_at)
+FROM refresh_tokens
+GROUP BY family_id, user_id;
+
+ALTER TABLE refresh_tokens RENAME COLUMN family_id TO session_id;
+
+ALTER INDEX idx_refresh_tokens_family_id RENAME TO idx_refresh_tokens_session_id;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT fk_session
+    FOREIGN KEY(session_id)
+    REFERENCES sessions(id)
+    ON DELETE CASCADE;
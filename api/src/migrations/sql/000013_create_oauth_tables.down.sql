@@ -0,0 +1,7 @@
+ALTER TABLE sessions
+    DROP CONSTRAINT IF EXISTS fk_client,
+    DROP COLUMN IF EXISTS scopes,
+    DROP COLUMN IF EXISTS client_id;
+
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
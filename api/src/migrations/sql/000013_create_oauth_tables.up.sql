@@ -0,0 +1,45 @@
+CREATE TABLE oauth_clients (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    secret_hash VARCHAR(64),
+    redirect_uris TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_oauth_clients_user_id ON oauth_clients(user_id);
+
+CREATE TABLE oauth_authorization_codes (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL,
+    user_id INT NOT NULL,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_client
+    FOREIGN KEY(client_id)
+    REFERENCES oauth_clients(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+ALTER TABLE sessions
+    ADD COLUMN client_id VARCHAR(64),
+    ADD COLUMN scopes TEXT,
+    ADD CONSTRAINT fk_client
+    FOREIGN KEY(client_id)
+    REFERENCES oauth_clients(id)
+    ON DELETE CASCADE;
@@ -0,0 +1,14 @@
+CREATE TABLE magic_links (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL,
+    nonce_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_magic_links_user_id ON magic_links(user_id);
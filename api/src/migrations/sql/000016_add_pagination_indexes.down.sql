@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_followers_follower_created_at;
+DROP INDEX IF EXISTS idx_followers_user_created_at;
+DROP INDEX IF EXISTS idx_publications_author_created_at_id;
+DROP INDEX IF EXISTS idx_users_created_at_id;
+
+ALTER TABLE followers DROP COLUMN IF EXISTS created_at;
+ALTER TABLE publications ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN created_at DROP NOT NULL;
@@ -0,0 +1,13 @@
+UPDATE users SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+ALTER TABLE users ALTER COLUMN created_at SET NOT NULL;
+
+UPDATE publications SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+ALTER TABLE publications ALTER COLUMN created_at SET NOT NULL;
+
+ALTER TABLE followers
+    ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX idx_users_created_at_id ON users(created_at DESC, id DESC);
+CREATE INDEX idx_publications_author_created_at_id ON publications(author_id, created_at DESC, id DESC);
+CREATE INDEX idx_followers_user_created_at ON followers(user_id, created_at DESC, follower_id DESC);
+CREATE INDEX idx_followers_follower_created_at ON followers(follower_id, created_at DESC, user_id DESC);
@@ -0,0 +1,14 @@
+CREATE TABLE two_factor_challenges (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_two_factor_challenges_user_id ON two_factor_challenges(user_id);
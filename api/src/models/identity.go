@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type OIDCLogin struct {
+	ID           uint64
+	Provider     string
+	StateHash    string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type UserIdentity struct {
+	ID        uint64
+	UserID    uint64
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
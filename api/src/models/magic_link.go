@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type MagicLink struct {
+	ID        string
+	UserID    uint64
+	NonceHash string
+	ExpiresAt time.Time
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+type MagicLinkIssued struct {
+	Nonce     string `json:"nonce"`
+	ExpiresIn int64  `json:"expiresIn"`
+}
+
+type MagicLinkVerify struct {
+	Token string `json:"token"`
+	Nonce string `json:"nonce,omitempty"`
+}
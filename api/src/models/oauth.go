@@ -0,0 +1,128 @@
+package models
+
+import (
+	"api/src/apperrors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type OAuthClient struct {
+	ID           string     `json:"clientId"`
+	UserID       uint64     `json:"-"`
+	Name         string     `json:"name"`
+	Confidential bool       `json:"confidential"`
+	Secret       string     `json:"clientSecret,omitempty"`
+	SecretHash   string     `json:"-"`
+	RedirectURIs []string   `json:"redirectUris"`
+	Scopes       []string   `json:"scopes"`
+	RevokedAt    *time.Time `json:"-"`
+	CreatedAt    time.Time  `json:"createdAt,omitempty"`
+}
+
+type AuthorizationCode struct {
+	ID            uint64
+	ClientID      string
+	UserID        uint64
+	CodeHash      string
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+type AuthorizationRequest struct {
+	ResponseType        string `json:"responseType"`
+	ClientID            string `json:"clientId"`
+	RedirectURI         string `json:"redirectUri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"codeChallenge"`
+	CodeChallengeMethod string `json:"codeChallengeMethod"`
+	Approved            bool   `json:"approved"`
+}
+
+type ConsentScope struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type Consent struct {
+	ClientID    string         `json:"clientId"`
+	ClientName  string         `json:"clientName"`
+	RedirectURI string         `json:"redirectUri"`
+	Scopes      []ConsentScope `json:"scopes"`
+	State       string         `json:"state,omitempty"`
+}
+
+type AuthorizationRedirect struct {
+	RedirectURI string `json:"redirectUri"`
+}
+
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+func (client *OAuthClient) Prepare() error {
+	client.Name = strings.TrimSpace(client.Name)
+
+	var fields []apperrors.FieldError
+
+	if client.Name == "" {
+		fields = append(fields, apperrors.Field("name", "required", "name.required"))
+	} else if len(client.Name) > 100 {
+		fields = append(fields, apperrors.Field("name", "too_long", "name.too_long"))
+	}
+
+	if len(client.RedirectURIs) == 0 {
+		fields = append(fields, apperrors.Field("redirectUris", "required", "oauth.redirect_required"))
+	}
+
+	for _, redirectURI := range client.RedirectURIs {
+		if field, ok := validateRedirectURI(redirectURI); !ok {
+			fields = append(fields, field)
+		}
+	}
+
+	if len(client.Scopes) == 0 {
+		fields = append(fields, apperrors.Field("scopes", "required", "scopes.required"))
+	}
+
+	return apperrors.Invalid(fields...)
+}
+
+func validateRedirectURI(redirectURI string) (apperrors.FieldError, bool) {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || !parsed.IsAbs() || parsed.Host == "" || parsed.Fragment != "" || strings.ContainsAny(redirectURI, " ") {
+		return apperrors.Field("redirectUris", "invalid_format", "oauth.redirect_invalid", redirectURI), false
+	}
+
+	if parsed.Scheme == "https" {
+		return apperrors.FieldError{}, true
+	}
+
+	host := parsed.Hostname()
+	if parsed.Scheme == "http" && (host == "localhost" || host == "127.0.0.1" || host == "::1") {
+		return apperrors.FieldError{}, true
+	}
+
+	return apperrors.Field("redirectUris", "insecure_scheme", "oauth.redirect_insecure", redirectURI), false
+}
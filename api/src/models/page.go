@@ -0,0 +1,37 @@
+package models
+
+import (
+	"api/src/apperrors"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint64    `json:"i"`
+}
+
+type PageRequest struct {
+	Limit int
+	After *Cursor
+}
+
+func (cursor Cursor) Encode() string {
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, apperrors.Invalid(apperrors.Field("cursor", "invalid", "page.cursor_invalid"))
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.ID == 0 || cursor.CreatedAt.IsZero() {
+		return nil, apperrors.Invalid(apperrors.Field("cursor", "invalid", "page.cursor_invalid"))
+	}
+
+	return &cursor, nil
+}
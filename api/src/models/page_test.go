@@ -0,0 +1,56 @@
+package models
+
+import (
+	"api/src/apperrors"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{CreatedAt: time.Date(2024, 5, 17, 10, 30, 0, 123456789, time.UTC), ID: 42}
+
+	encoded := cursor.Encode()
+	decoded, err := DecodeCursor(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.ID != cursor.ID || !decoded.CreatedAt.Equal(cursor.CreatedAt) {
+		t.Errorf("decoded %+v, want %+v", decoded, cursor)
+	}
+}
+
+func TestCursorIsURLSafe(t *testing.T) {
+	encoded := Cursor{CreatedAt: time.Now(), ID: 1<<63 - 1}.Encode()
+
+	for _, character := range encoded {
+		if character == '+' || character == '/' || character == '=' {
+			t.Fatalf("cursor %q is not URL safe", encoded)
+		}
+	}
+}
+
+func TestDecodeCursorRejectsInvalidValues(t *testing.T) {
+	cases := map[string]string{
+		"not base64":   "%%%",
+		"not json":     base64.RawURLEncoding.EncodeToString([]byte("cursor")),
+		"missing id":   base64.RawURLEncoding.EncodeToString([]byte(`{"t":"2024-05-17T10:30:00Z"}`)),
+		"missing time": base64.RawURLEncoding.EncodeToString([]byte(`{"i":42}`)),
+		"padded":       base64.URLEncoding.EncodeToString([]byte(`{"t":"2024-05-17T10:30:00Z","i":42}`)),
+	}
+
+	for name, value := range cases {
+		t.Run(name, func(t *testing.T) {
+			cursor, err := DecodeCursor(value)
+			if err == nil {
+				t.Fatalf("decoded %+v", cursor)
+			}
+
+			if !errors.Is(err, apperrors.ErrValidation) {
+				t.Errorf("error %v is not a validation error", err)
+			}
+		})
+	}
+}
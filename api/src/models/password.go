@@ -4,3 +4,12 @@ type Password struct {
 	New     string `json:"new"`
 	Current string `json:"current"`
 }
+
+type PasswordForgot struct {
+	Email string `json:"email"`
+}
+
+type PasswordReset struct {
+	Token string `json:"token"`
+	New   string `json:"new"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"api/src/apperrors"
+	"strings"
+	"time"
+)
+
+type PersonalAccessToken struct {
+	ID         uint64     `json:"id,omitempty"`
+	UserID     uint64     `json:"-"`
+	Name       string     `json:"name,omitempty"`
+	Token      string     `json:"token,omitempty"`
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"createdAt,omitempty"`
+}
+
+func (token *PersonalAccessToken) Prepare() error {
+	token.Name = strings.TrimSpace(token.Name)
+
+	var fields []apperrors.FieldError
+
+	if token.Name == "" {
+		fields = append(fields, apperrors.Field("name", "required", "name.required"))
+	} else if len(token.Name) > 100 {
+		fields = append(fields, apperrors.Field("name", "too_long", "name.too_long"))
+	}
+
+	if len(token.Scopes) == 0 {
+		fields = append(fields, apperrors.Field("scopes", "required", "scopes.required"))
+	}
+
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(time.Now()) {
+		fields = append(fields, apperrors.Field("expiresAt", "in_past", "token.expires_in_past"))
+	}
+
+	return apperrors.Invalid(fields...)
+}
@@ -1,7 +1,7 @@
 package models
 
 import (
-	"errors"
+	"api/src/apperrors"
 	"strings"
 	"time"
 )
@@ -26,15 +26,17 @@ func (publication *Publication) Prepare() error {
 }
 
 func (publication *Publication) validate() error {
+	var fields []apperrors.FieldError
+
 	if publication.Title == "" {
-		return errors.New("the title is required and cannot be empty")
+		fields = append(fields, apperrors.Field("title", "required", "publication.title"))
 	}
 
 	if publication.Content == "" {
-		return errors.New("the content is required and cannot be empty")
+		fields = append(fields, apperrors.Field("content", "required", "publication.content"))
 	}
 
-	return nil
+	return apperrors.Invalid(fields...)
 }
 
 func (publication *Publication) format() {
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+const EventLoginLockout = "login_lockout"
+
+type SecurityEvent struct {
+	ID        uint64
+	UserID    uint64
+	Type      string
+	IP        string
+	Detail    string
+	CreatedAt time.Time
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     uint64     `json:"-"`
+	UserAgent  string     `json:"userAgent"`
+	IP         string     `json:"ip"`
+	ClientID   string     `json:"clientId,omitempty"`
+	Scopes     []string   `json:"scopes,omitempty"`
+	Current    bool       `json:"current"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	RevokedAt  *time.Time `json:"-"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type RefreshToken struct {
+	ID        uint64
+	UserID    uint64
+	SessionID string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type TokenRefresh struct {
+	RefreshToken string `json:"refreshToken"`
+}
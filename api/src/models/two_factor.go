@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type TwoFactor struct {
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  int64
+}
+
+type TwoFactorChallenge struct {
+	ID        string
+	UserID    uint64
+	ExpiresAt time.Time
+}
+
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TwoFactorCode struct {
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
+
+type TwoFactorLogin struct {
+	MFAToken     string `json:"mfaToken"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"recoveryCodes"`
+}
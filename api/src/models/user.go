@@ -1,8 +1,9 @@
 package models
 
 import (
+	"api/src/apperrors"
+	"api/src/i18n"
 	"api/src/security"
-	"errors"
 	"strings"
 	"time"
 
@@ -10,12 +11,23 @@ import (
 )
 
 type User struct {
-	ID        uint64    `json:"id,omitempty"`
-	Name      string    `json:"name,omitempty"`
-	Nick      string    `json:"nick,omitempty"`
-	Email     string    `json:"email,omitempty"`
-	Password  string    `json:"password,omitempty"`
-	CreatedAt time.Time `json:"-"`
+	ID              uint64     `json:"id,omitempty"`
+	Name            string     `json:"name,omitempty"`
+	Nick            string     `json:"nick,omitempty"`
+	Email           string     `json:"email,omitempty"`
+	Password        string     `json:"password,omitempty"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+	Role            string     `json:"role,omitempty"`
+	Locale          string     `json:"locale,omitempty"`
+	CreatedAt       time.Time  `json:"-"`
+}
+
+type UserRole struct {
+	Role string `json:"role"`
+}
+
+type EmailVerification struct {
+	Token string `json:"token"`
 }
 
 func (user *User) Prepare(stage string) error {
@@ -31,27 +43,43 @@ func (user *User) Prepare(stage string) error {
 }
 
 func (user *User) validate(stage string) error {
+	var fields []apperrors.FieldError
+
 	if user.Name == "" {
-		return errors.New("name is required and cannot be blank")
+		fields = append(fields, apperrors.Field("name", "required", "name.required"))
 	}
 
 	if user.Nick == "" {
-		return errors.New("nick is required and cannot be blank")
+		fields = append(fields, apperrors.Field("nick", "required", "user.nick.required"))
 	}
 
 	if user.Email == "" {
-		return errors.New("email is required and cannot be blank")
+		fields = append(fields, apperrors.Field("email", "required", "user.email.required"))
+	} else if err := checkmail.ValidateFormat(user.Email); err != nil {
+		fields = append(fields, apperrors.Field("email", "invalid_format", "user.email.invalid"))
 	}
 
-	if err := checkmail.ValidateFormat(user.Email); err != nil {
-		return errors.New("the provided email is invalid")
+	if user.Locale != "" {
+		if locale, ok := i18n.Supported(user.Locale); ok {
+			user.Locale = locale
+		} else {
+			fields = append(fields, apperrors.Field("locale", "unsupported", "locale.unsupported", user.Locale))
+		}
 	}
 
-	if stage == "registration" && user.Password == "" {
-		return errors.New("password is required and cannot be blank")
+	if stage == "registration" {
+		if user.Password == "" {
+			fields = append(fields, apperrors.Field("password", "required", "user.password.required"))
+		} else if err := security.CheckPasswordPolicy(user.Password); err != nil {
+			passwordFields := apperrors.FieldErrors(err)
+			if passwordFields == nil {
+				return err
+			}
+			fields = append(fields, passwordFields...)
+		}
 	}
 
-	return nil
+	return apperrors.Invalid(fields...)
 }
 
 func (user *User) format(stage string) error {
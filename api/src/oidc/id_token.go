@@ -0,0 +1,154 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const keysRefetchInterval = time.Minute
+
+func (p *Provider) verifyIDToken(ctx context.Context, rawToken string) (jwt.MapClaims, error) {
+	if rawToken == "" {
+		return nil, fmt.Errorf("%s: token exchange returned no ID token", p.config.Name)
+	}
+
+	token, err := jwt.Parse(rawToken, func(token *jwt.Token) (interface{}, error) {
+		return p.signingKey(ctx, token)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid ID token: %w", p.config.Name, err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("%s: invalid ID token", p.config.Name)
+	}
+
+	if strings.TrimSuffix(claimString(claims, "iss"), "/") != strings.TrimSuffix(p.config.IssuerURL, "/") {
+		return nil, fmt.Errorf("%s: ID token issuer %q does not match %q", p.config.Name, claimString(claims, "iss"), p.config.IssuerURL)
+	}
+
+	if !hasAudience(claims["aud"], p.config.ClientID) {
+		return nil, fmt.Errorf("%s: ID token was not issued for this client", p.config.Name)
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%s: ID token has no expiration", p.config.Name)
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) signingKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+
+	keyID, _ := token.Header["kid"].(string)
+	if key := p.cachedKey(keyID); key != nil {
+		return key, nil
+	}
+
+	if p.claimKeysRefetch() {
+		if err := p.loadKeys(ctx); err != nil {
+			return nil, err
+		}
+
+		if key := p.cachedKey(keyID); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", keyID)
+}
+
+func (p *Provider) cachedKey(keyID string) *rsa.PublicKey {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.keys[keyID]
+}
+
+func (p *Provider) claimKeysRefetch() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.keysFetchedAt.IsZero() && time.Since(p.keysFetchedAt) < keysRefetchInterval {
+		return false
+	}
+
+	p.keysFetchedAt = time.Now()
+	return true
+}
+
+func (p *Provider) loadKeys(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.JWKSURL, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	var document struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.do(request, &document); err != nil {
+		return err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, key := range document.Keys {
+		if key.KeyType != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		modulus, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return fmt.Errorf("%s: invalid modulus for key %q", p.config.Name, key.KeyID)
+		}
+
+		exponent, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil || len(exponent) == 0 || len(exponent) > 4 {
+			return fmt.Errorf("%s: invalid exponent for key %q", p.config.Name, key.KeyID)
+		}
+
+		keys[key.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}
+	}
+
+	p.mutex.Lock()
+	p.keys = keys
+	p.mutex.Unlock()
+
+	return nil
+}
+
+func hasAudience(audience interface{}, clientID string) bool {
+	switch value := audience.(type) {
+	case string:
+		return value == clientID
+	case []interface{}:
+		for _, entry := range value {
+			if entry == clientID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,357 @@
+package oidc
+
+import (
+	"api/src/apperrors"
+	"api/src/config"
+	"api/src/security"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var ErrUnknownProvider = apperrors.NotFound("oidc.unknown_provider")
+
+var presets = map[string]config.OIDCProvider{
+	"github": {
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Scopes:      []string{"read:user", "user:email"},
+	},
+	"gitlab": {
+		IssuerURL: "https://gitlab.com",
+		Scopes:    []string{"openid", "profile", "email"},
+	},
+}
+
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Name          string
+}
+
+type Token struct {
+	AccessToken string
+	IDToken     string
+}
+
+type Provider struct {
+	config config.OIDCProvider
+	client *http.Client
+	mutex  sync.Mutex
+	keys   map[string]*rsa.PublicKey
+
+	keysFetchedAt time.Time
+}
+
+type Providers map[string]*Provider
+
+func NewProviders() Providers {
+	providers := Providers{}
+	for _, provider := range config.OIDCProviders {
+		providers[provider.Name] = NewProvider(provider, &http.Client{Timeout: 10 * time.Second})
+	}
+
+	return providers
+}
+
+func (p Providers) Get(name string) (*Provider, error) {
+	provider, ok := p[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	return provider, nil
+}
+
+func NewProvider(cfg config.OIDCProvider, client *http.Client) *Provider {
+	if preset, ok := presets[cfg.Name]; ok {
+		if cfg.IssuerURL == "" {
+			cfg.IssuerURL = preset.IssuerURL
+		}
+		if cfg.AuthURL == "" {
+			cfg.AuthURL = preset.AuthURL
+		}
+		if cfg.TokenURL == "" {
+			cfg.TokenURL = preset.TokenURL
+		}
+		if cfg.UserInfoURL == "" {
+			cfg.UserInfoURL = preset.UserInfoURL
+		}
+		if cfg.EmailsURL == "" {
+			cfg.EmailsURL = preset.EmailsURL
+		}
+		if cfg.JWKSURL == "" {
+			cfg.JWKSURL = preset.JWKSURL
+		}
+		if len(cfg.Scopes) == 0 {
+			cfg.Scopes = preset.Scopes
+		}
+	}
+
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+
+	return &Provider{config: cfg, client: client}
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+func (p *Provider) AuthCodeURL(ctx context.Context, state, verifier string) (string, error) {
+	if err := p.discover(ctx); err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {security.PKCEChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(p.config.AuthURL, "?") {
+		separator = "&"
+	}
+
+	return p.config.AuthURL + separator + query.Encode(), nil
+}
+
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (Token, error) {
+	if err := p.discover(ctx); err != nil {
+		return Token{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"client_secret": {p.config.ClientSecret},
+		"code_verifier": {verifier},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.do(request, &token); err != nil {
+		return Token{}, err
+	}
+
+	if token.Error != "" {
+		return Token{}, fmt.Errorf("%s: token exchange failed: %s", p.config.Name, strings.TrimSpace(token.Error+" "+token.ErrorDescription))
+	}
+
+	if token.AccessToken == "" {
+		return Token{}, fmt.Errorf("%s: token exchange returned no access token", p.config.Name)
+	}
+
+	return Token{AccessToken: token.AccessToken, IDToken: token.IDToken}, nil
+}
+
+func (p *Provider) Identity(ctx context.Context, token Token) (Identity, error) {
+	if err := p.discover(ctx); err != nil {
+		return Identity{}, err
+	}
+
+	var idClaims jwt.MapClaims
+	if p.config.IssuerURL != "" {
+		var err error
+		if idClaims, err = p.verifyIDToken(ctx, token.IDToken); err != nil {
+			return Identity{}, err
+		}
+	}
+
+	var claims map[string]interface{}
+	if err := p.get(ctx, p.config.UserInfoURL, token.AccessToken, &claims); err != nil {
+		return Identity{}, err
+	}
+
+	identity := Identity{
+		Provider:      p.config.Name,
+		Subject:       claimString(claims, "sub", "id"),
+		Email:         claimString(claims, "email"),
+		EmailVerified: claimBool(claims, "email_verified"),
+		Username:      claimString(claims, "preferred_username", "nickname", "login", "username"),
+		Name:          claimString(claims, "name"),
+	}
+
+	if identity.Subject == "" {
+		return Identity{}, fmt.Errorf("%s: user info has no subject", p.config.Name)
+	}
+
+	if idClaims != nil {
+		if identity.Subject != claimString(idClaims, "sub") {
+			return Identity{}, fmt.Errorf("%s: user info subject does not match the ID token", p.config.Name)
+		}
+
+		if identity.Email == "" {
+			identity.Email = claimString(idClaims, "email")
+			identity.EmailVerified = claimBool(idClaims, "email_verified")
+		}
+	}
+
+	if p.config.EmailsURL != "" {
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		if err := p.get(ctx, p.config.EmailsURL, token.AccessToken, &emails); err != nil {
+			return Identity{}, err
+		}
+
+		identity.Email, identity.EmailVerified = "", false
+		for _, email := range emails {
+			if email.Primary {
+				identity.Email, identity.EmailVerified = email.Email, email.Verified
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+func (p *Provider) discover(ctx context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.config.AuthURL != "" && p.config.TokenURL != "" && p.config.UserInfoURL != "" &&
+		(p.config.IssuerURL == "" || p.config.JWKSURL != "") {
+		return nil
+	}
+
+	if p.config.IssuerURL == "" {
+		return fmt.Errorf("%s: an issuer URL or explicit endpoints are required", p.config.Name)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.config.IssuerURL, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return err
+	}
+
+	var document struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := p.do(request, &document); err != nil {
+		return err
+	}
+
+	if strings.TrimSuffix(document.Issuer, "/") != strings.TrimSuffix(p.config.IssuerURL, "/") {
+		return fmt.Errorf("%s: discovery issuer %q does not match %q", p.config.Name, document.Issuer, p.config.IssuerURL)
+	}
+
+	if p.config.AuthURL == "" {
+		p.config.AuthURL = document.AuthorizationEndpoint
+	}
+	if p.config.TokenURL == "" {
+		p.config.TokenURL = document.TokenEndpoint
+	}
+	if p.config.UserInfoURL == "" {
+		p.config.UserInfoURL = document.UserInfoEndpoint
+	}
+	if p.config.JWKSURL == "" {
+		p.config.JWKSURL = document.JWKSURI
+	}
+
+	if p.config.AuthURL == "" || p.config.TokenURL == "" || p.config.UserInfoURL == "" || p.config.JWKSURL == "" {
+		return fmt.Errorf("%s: discovery document is missing endpoints", p.config.Name)
+	}
+
+	return nil
+}
+
+func (p *Provider) get(ctx context.Context, endpoint, accessToken string, target interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	request.Header.Set("Accept", "application/json")
+
+	return p.do(request, target)
+}
+
+func (p *Provider) do(request *http.Request, target interface{}) error {
+	response, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.config.Name, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.config.Name, err)
+	}
+
+	if response.StatusCode >= http.StatusMultipleChoices && response.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("%s: %s returned %s", p.config.Name, request.URL.Path, response.Status)
+	}
+
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("%s: invalid response from %s: %w", p.config.Name, request.URL.Path, err)
+	}
+
+	return nil
+}
+
+func claimString(claims map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		switch value := claims[name].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+
+	return ""
+}
+
+func claimBool(claims map[string]interface{}, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		verified, _ := strconv.ParseBool(value)
+		return verified
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+func ParseLimit(value string) (Limit, error) {
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must be in the form requests/window", value)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("limit %q has an invalid request count", value)
+	}
+
+	duration, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || duration <= 0 {
+		return Limit{}, fmt.Errorf("limit %q has an invalid window", value)
+	}
+
+	return Limit{Requests: count, Window: duration}, nil
+}
+
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(math.Ceil(l.Window.Seconds())))
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+func (l Limit) Take(bucket Bucket, now time.Time) (Bucket, Result) {
+	capacity := float64(l.Requests)
+
+	tokens := capacity
+	if !bucket.UpdatedAt.IsZero() {
+		elapsed := max(now.Sub(bucket.UpdatedAt).Seconds(), 0)
+		tokens = math.Min(capacity, bucket.Tokens+elapsed*l.rate())
+	}
+
+	result := Result{Limit: l.Requests}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.wait(1 - tokens)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = l.wait(capacity - tokens)
+
+	return Bucket{Tokens: tokens, UpdatedAt: now}, result
+}
+
+func (l Limit) wait(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate() * float64(time.Second))
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimitTakeDrainsAndRefills(t *testing.T) {
+	limit := Limit{Requests: 3, Window: 3 * time.Second}
+	now := time.Unix(1700000000, 0)
+
+	var bucket Bucket
+	var result Result
+	for i := 0; i < 3; i++ {
+		bucket, result = limit.Take(bucket, now)
+		if !result.Allowed {
+			t.Fatalf("request %d rejected", i+1)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("request %d left %d remaining, want %d", i+1, result.Remaining, 2-i)
+		}
+	}
+
+	bucket, result = limit.Take(bucket, now)
+	if result.Allowed {
+		t.Fatal("request over the limit allowed")
+	}
+	if result.RetryAfter != time.Second {
+		t.Errorf("retry after %s, want 1s", result.RetryAfter)
+	}
+	if result.Reset != 3*time.Second {
+		t.Errorf("reset after %s, want 3s", result.Reset)
+	}
+
+	bucket, result = limit.Take(bucket, now.Add(500*time.Millisecond))
+	if result.Allowed {
+		t.Fatal("request allowed before a token was refilled")
+	}
+
+	_, result = limit.Take(bucket, now.Add(time.Second))
+	if !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("request after refill: allowed=%v remaining=%d", result.Allowed, result.Remaining)
+	}
+}
+
+func TestLimitTakeCapsRefillAtCapacity(t *testing.T) {
+	limit := Limit{Requests: 2, Window: time.Second}
+	now := time.Unix(1700000000, 0)
+
+	bucket, _ := limit.Take(Bucket{}, now)
+	_, result := limit.Take(bucket, now.Add(time.Hour))
+
+	if !result.Allowed || result.Remaining != 1 {
+		t.Errorf("after an idle hour: allowed=%v remaining=%d, want capacity minus one", result.Allowed, result.Remaining)
+	}
+}
+
+func TestLimitTakeIgnoresClockGoingBackwards(t *testing.T) {
+	limit := Limit{Requests: 1, Window: time.Minute}
+	now := time.Unix(1700000000, 0)
+
+	bucket, _ := limit.Take(Bucket{}, now)
+	if _, result := limit.Take(bucket, now.Add(-time.Hour)); result.Allowed {
+		t.Error("request allowed after the clock went backwards")
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit(" 30 / 1m ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit != (Limit{Requests: 30, Window: time.Minute}) {
+		t.Errorf("parsed %+v", limit)
+	}
+	if limit.String() != "30;w=60" {
+		t.Errorf("policy %q, want 30;w=60", limit.String())
+	}
+
+	for _, value := range []string{"", "30", "0/1m", "-1/1m", "x/1m", "30/0s", "30/forever"} {
+		if _, err := ParseLimit(value); err == nil {
+			t.Errorf("%q parsed without error", value)
+		}
+	}
+}
+
+func TestMemoryStoreKeepsKeysApart(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 1, Window: time.Minute}
+
+	if result, _ := store.Take(context.Background(), "ip:10.0.0.1", limit); !result.Allowed {
+		t.Fatal("first request rejected")
+	}
+	if result, _ := store.Take(context.Background(), "ip:10.0.0.1", limit); result.Allowed {
+		t.Fatal("second request for the same key allowed")
+	}
+	if result, _ := store.Take(context.Background(), "ip:10.0.0.2", limit); !result.Allowed {
+		t.Fatal("request for another key rejected")
+	}
+}
+
+func TestMemoryStoreSweepsExpiredBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 1, Window: time.Millisecond}
+
+	store.Take(context.Background(), "ip:10.0.0.1", limit)
+	store.lastSweep = time.Now().Add(-2 * sweepInterval)
+	time.Sleep(5 * time.Millisecond)
+	store.Take(context.Background(), "ip:10.0.0.2", limit)
+
+	if _, ok := store.buckets["ip:10.0.0.1"]; ok {
+		t.Error("expired bucket was not swept")
+	}
+}
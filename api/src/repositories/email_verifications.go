@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+type (
+	EmailVerificationRepository interface {
+		CreateEmailVerification(ctx context.Context, userID uint64, email, tokenHash string, expiresAt time.Time) error
+		ConsumeEmailVerification(ctx context.Context, tokenHash string) (uint64, string, error)
+	}
+
+	emailVerificationRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewEmailVerificationRepository(db *sql.DB, logger *slog.Logger) EmailVerificationRepository {
+	return &emailVerificationRepository{db: db, logger: logger}
+}
+
+func (e *emailVerificationRepository) CreateEmailVerification(ctx context.Context, userID uint64, email, tokenHash string, expiresAt time.Time) (err error) {
+	ctx, finish := startSpan(ctx, e.logger, "EmailVerificationRepository.CreateEmailVerification", "UPDATE")
+	defer finish(&err)
+
+	statement, err := e.db.PrepareContext(ctx,
+		"UPDATE email_verifications SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.ExecContext(ctx, userID); err != nil {
+		return err
+	}
+
+	insert, err := e.db.PrepareContext(ctx,
+		"INSERT INTO email_verifications (user_id, email, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+	)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	if _, err = insert.ExecContext(ctx, userID, email, tokenHash, expiresAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (e *emailVerificationRepository) ConsumeEmailVerification(ctx context.Context, tokenHash string) (_ uint64, _ string, err error) {
+	ctx, finish := startSpan(ctx, e.logger, "EmailVerificationRepository.ConsumeEmailVerification", "UPDATE")
+	defer finish(&err)
+
+	row, err := e.db.QueryContext(ctx, `
+		UPDATE email_verifications SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING user_id, email`, tokenHash, time.Now().UTC())
+	if err != nil {
+		return 0, "", err
+	}
+	defer row.Close()
+
+	var userID uint64
+	var email string
+	if row.Next() {
+		if err := row.Scan(&userID, &email); err != nil {
+			return 0, "", err
+		}
+	}
+
+	return userID, email, nil
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"api/src/apperrors"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	checkViolation      = "23514"
+)
+
+var conflictKeys = map[string]string{
+	"users_email_key": "email.in_use",
+}
+
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case uniqueViolation:
+		if key, ok := conflictKeys[pqErr.Constraint]; ok {
+			return apperrors.Conflict(key)
+		}
+		return apperrors.Conflict("record.exists", pqErr.Table)
+	case foreignKeyViolation:
+		return apperrors.NotFound("record.missing")
+	case checkViolation:
+		return apperrors.Validation("record.invalid", pqErr.Constraint)
+	}
+
+	return err
+}
+
+func requireAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return notFound
+	}
+
+	return nil
+}
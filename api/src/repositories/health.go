@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+)
+
+type (
+	HealthRepository interface {
+		Ping(ctx context.Context) error
+		MigrationVersion(ctx context.Context) (uint, bool, error)
+	}
+
+	healthRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewHealthRepository(db *sql.DB, logger *slog.Logger) HealthRepository {
+	return &healthRepository{db: db, logger: logger}
+}
+
+func (h *healthRepository) Ping(ctx context.Context) (err error) {
+	ctx, finish := startSpan(ctx, h.logger, "HealthRepository.Ping", "PING")
+	defer finish(&err)
+
+	return h.db.PingContext(ctx)
+}
+
+func (h *healthRepository) MigrationVersion(ctx context.Context) (_ uint, _ bool, err error) {
+	ctx, finish := startSpan(ctx, h.logger, "HealthRepository.MigrationVersion", "SELECT")
+	defer finish(&err)
+
+	row, err := h.db.QueryContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1")
+	if err != nil {
+		return 0, false, err
+	}
+	defer row.Close()
+
+	var version uint
+	var dirty bool
+	if row.Next() {
+		if err := row.Scan(&version, &dirty); err != nil {
+			return 0, false, err
+		}
+	}
+	return version, dirty, row.Err()
+}
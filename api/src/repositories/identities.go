@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"api/src/models"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+type (
+	IdentityRepository interface {
+		CreateLogin(ctx context.Context, login models.OIDCLogin) error
+		ConsumeLogin(ctx context.Context, provider, stateHash string) (models.OIDCLogin, error)
+		GetUserIDByIdentity(ctx context.Context, provider, subject string) (uint64, error)
+		LinkIdentity(ctx context.Context, identity models.UserIdentity) error
+	}
+
+	identityRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewIdentityRepository(db *sql.DB, logger *slog.Logger) IdentityRepository {
+	return &identityRepository{db: db, logger: logger}
+}
+
+func (i *identityRepository) CreateLogin(ctx context.Context, login models.OIDCLogin) (err error) {
+	ctx, finish := startSpan(ctx, i.logger, "IdentityRepository.CreateLogin", "INSERT")
+	defer finish(&err)
+
+	statement, err := i.db.PrepareContext(ctx,
+		"INSERT INTO oidc_logins (provider, state_hash, code_verifier, expires_at) VALUES ($1, $2, $3, $4)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx, login.Provider, login.StateHash, login.CodeVerifier, login.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (i *identityRepository) ConsumeLogin(ctx context.Context, provider, stateHash string) (_ models.OIDCLogin, err error) {
+	ctx, finish := startSpan(ctx, i.logger, "IdentityRepository.ConsumeLogin", "UPDATE")
+	defer finish(&err)
+
+	var login models.OIDCLogin
+
+	row, err := i.db.QueryContext(ctx, `
+		UPDATE oidc_logins SET used_at = CURRENT_TIMESTAMP
+		WHERE provider = $1 AND state_hash = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING id, provider, state_hash, code_verifier, expires_at`, provider, stateHash, time.Now().UTC())
+	if err != nil {
+		return login, err
+	}
+	defer row.Close()
+
+	if row.Next() {
+		if err := row.Scan(&login.ID, &login.Provider, &login.StateHash, &login.CodeVerifier, &login.ExpiresAt); err != nil {
+			return login, err
+		}
+	}
+
+	return login, nil
+}
+
+func (i *identityRepository) GetUserIDByIdentity(ctx context.Context, provider, subject string) (_ uint64, err error) {
+	ctx, finish := startSpan(ctx, i.logger, "IdentityRepository.GetUserIDByIdentity", "SELECT")
+	defer finish(&err)
+
+	row, err := i.db.QueryContext(ctx, "SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2", provider, subject)
+	if err != nil {
+		return 0, err
+	}
+	defer row.Close()
+
+	var userID uint64
+	if row.Next() {
+		if err := row.Scan(&userID); err != nil {
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+func (i *identityRepository) LinkIdentity(ctx context.Context, identity models.UserIdentity) (err error) {
+	ctx, finish := startSpan(ctx, i.logger, "IdentityRepository.LinkIdentity", "INSERT")
+	defer finish(&err)
+
+	statement, err := i.db.PrepareContext(ctx,
+		"INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4) ON CONFLICT (provider, subject) DO NOTHING",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
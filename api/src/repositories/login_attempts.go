@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"api/src/logging"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+type (
+	LoginAttemptRepository interface {
+		GetBlockedUntil(ctx context.Context, keys ...string) (time.Time, error)
+		RecordFailure(ctx context.Context, key string, window time.Duration) (int, error)
+		Block(ctx context.Context, key string, until time.Time) error
+		Reset(ctx context.Context, key string) error
+	}
+
+	loginAttemptRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewLoginAttemptRepository(db *sql.DB, logger *slog.Logger) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db, logger: logger}
+}
+
+func (l *loginAttemptRepository) GetBlockedUntil(ctx context.Context, keys ...string) (_ time.Time, err error) {
+	ctx, finish := startSpan(ctx, l.logger, "LoginAttemptRepository.GetBlockedUntil", "SELECT")
+	defer finish(&err)
+
+	var blockedUntil time.Time
+
+	for _, key := range keys {
+		row, err := l.db.QueryContext(ctx, "SELECT blocked_until FROM login_attempts WHERE key = $1 AND blocked_until IS NOT NULL", key)
+		if err != nil {
+			return blockedUntil, err
+		}
+
+		if row.Next() {
+			var until time.Time
+			if err := row.Scan(&until); err != nil {
+				row.Close()
+				return blockedUntil, err
+			}
+			if until.After(blockedUntil) {
+				blockedUntil = until
+			}
+		}
+		row.Close()
+	}
+
+	return blockedUntil, nil
+}
+
+func (l *loginAttemptRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (_ int, err error) {
+	ctx, finish := startSpan(ctx, l.logger, "LoginAttemptRepository.RecordFailure", "INSERT")
+	defer finish(&err)
+
+	now := time.Now().UTC()
+
+	row, err := l.db.QueryContext(ctx, `
+		INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at < $3 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = $2
+		RETURNING failures`, key, now, now.Add(-window))
+	if err != nil {
+		return 0, err
+	}
+	defer row.Close()
+
+	var failures int
+	if row.Next() {
+		if err := row.Scan(&failures); err != nil {
+			return 0, err
+		}
+	}
+
+	return failures, nil
+}
+
+func (l *loginAttemptRepository) Block(ctx context.Context, key string, until time.Time) (err error) {
+	ctx, finish := startSpan(ctx, l.logger, "LoginAttemptRepository.Block", "UPDATE")
+	defer finish(&err)
+
+	statement, err := l.db.PrepareContext(ctx, "UPDATE login_attempts SET blocked_until = $1 WHERE key = $2")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx, until, key)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, l.logger).DebugContext(ctx, "login key blocked", slog.String("key", key), slog.Time("until", until))
+
+	return nil
+}
+
+func (l *loginAttemptRepository) Reset(ctx context.Context, key string) (err error) {
+	ctx, finish := startSpan(ctx, l.logger, "LoginAttemptRepository.Reset", "DELETE")
+	defer finish(&err)
+
+	statement, err := l.db.PrepareContext(ctx, "DELETE FROM login_attempts WHERE key = $1")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
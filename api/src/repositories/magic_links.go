@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"api/src/logging"
+	"api/src/models"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+type (
+	MagicLinkRepository interface {
+		CreateMagicLink(ctx context.Context, link models.MagicLink) error
+		ConsumeMagicLink(ctx context.Context, id string, userID uint64, nonceHash string) (bool, error)
+	}
+
+	magicLinkRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewMagicLinkRepository(db *sql.DB, logger *slog.Logger) MagicLinkRepository {
+	return &magicLinkRepository{db: db, logger: logger}
+}
+
+func (m *magicLinkRepository) CreateMagicLink(ctx context.Context, link models.MagicLink) (err error) {
+	ctx, finish := startSpan(ctx, m.logger, "MagicLinkRepository.CreateMagicLink", "INSERT")
+	defer finish(&err)
+
+	statement, err := m.db.PrepareContext(ctx,
+		"INSERT INTO magic_links (id, user_id, nonce_hash, expires_at) VALUES ($1, $2, $3, $4)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx, link.ID, link.UserID, link.NonceHash, link.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *magicLinkRepository) ConsumeMagicLink(ctx context.Context, id string, userID uint64, nonceHash string) (_ bool, err error) {
+	ctx, finish := startSpan(ctx, m.logger, "MagicLinkRepository.ConsumeMagicLink", "UPDATE")
+	defer finish(&err)
+
+	statement, err := m.db.PrepareContext(ctx, `
+		UPDATE magic_links SET used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND nonce_hash = $3 AND used_at IS NULL AND expires_at > $4`,
+	)
+	if err != nil {
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.ExecContext(ctx, id, userID, nonceHash, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if affected == 0 {
+		logging.FromContext(ctx, m.logger).DebugContext(ctx, "magic link not consumed", slog.String("link_id", id), slog.Uint64("user_id", userID))
+	}
+
+	return affected == 1, nil
+}
@@ -0,0 +1,248 @@
+package repositories
+
+import (
+	"api/src/logging"
+	"api/src/models"
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type (
+	OAuthRepository interface {
+		CreateClient(ctx context.Context, client models.OAuthClient) error
+		GetClient(ctx context.Context, clientID string) (models.OAuthClient, error)
+		GetClients(ctx context.Context, userID uint64) ([]models.OAuthClient, error)
+		RevokeClient(ctx context.Context, userID uint64, clientID string) (bool, error)
+		CreateAuthorizationCode(ctx context.Context, code models.AuthorizationCode) error
+		ConsumeAuthorizationCode(ctx context.Context, codeHash string) (models.AuthorizationCode, error)
+	}
+
+	oauthRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewOAuthRepository(db *sql.DB, logger *slog.Logger) OAuthRepository {
+	return &oauthRepository{db: db, logger: logger}
+}
+
+func (o *oauthRepository) CreateClient(ctx context.Context, client models.OAuthClient) (err error) {
+	ctx, finish := startSpan(ctx, o.logger, "OAuthRepository.CreateClient", "INSERT")
+	defer finish(&err)
+
+	statement, err := o.db.PrepareContext(ctx,
+		"INSERT INTO oauth_clients (id, user_id, name, secret_hash, redirect_uris, scopes) VALUES ($1, $2, $3, $4, $5, $6)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx,
+		client.ID,
+		client.UserID,
+		client.Name,
+		sql.NullString{String: client.SecretHash, Valid: client.SecretHash != ""},
+		strings.Join(client.RedirectURIs, " "),
+		strings.Join(client.Scopes, " "),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *oauthRepository) GetClient(ctx context.Context, clientID string) (_ models.OAuthClient, err error) {
+	ctx, finish := startSpan(ctx, o.logger, "OAuthRepository.GetClient", "SELECT")
+	defer finish(&err)
+
+	var client models.OAuthClient
+
+	row, err := o.db.QueryContext(ctx, `
+		SELECT id, user_id, name, COALESCE(secret_hash, ''), redirect_uris, scopes, revoked_at, created_at
+		FROM oauth_clients
+		WHERE id = $1`, clientID)
+	if err != nil {
+		return client, err
+	}
+	defer row.Close()
+
+	if row.Next() {
+		if err := scanOAuthClient(row, &client); err != nil {
+			return client, err
+		}
+	}
+
+	return client, nil
+}
+
+func (o *oauthRepository) GetClients(ctx context.Context, userID uint64) (_ []models.OAuthClient, err error) {
+	ctx, finish := startSpan(ctx, o.logger, "OAuthRepository.GetClients", "SELECT")
+	defer finish(&err)
+
+	rows, err := o.db.QueryContext(ctx, `
+		SELECT id, user_id, name, COALESCE(secret_hash, ''), redirect_uris, scopes, revoked_at, created_at
+		FROM oauth_clients
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []models.OAuthClient{}
+	for rows.Next() {
+		var client models.OAuthClient
+		if err := scanOAuthClient(rows, &client); err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+func (o *oauthRepository) RevokeClient(ctx context.Context, userID uint64, clientID string) (_ bool, err error) {
+	ctx, finish := startSpan(ctx, o.logger, "OAuthRepository.RevokeClient", "UPDATE")
+	defer finish(&err)
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE oauth_clients SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		clientID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if affected == 0 {
+		return false, nil
+	}
+
+	result, err = tx.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE client_id = $1 AND revoked_at IS NULL",
+		clientID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if revoked, err := result.RowsAffected(); err == nil {
+		logging.FromContext(ctx, o.logger).DebugContext(ctx, "oauth client sessions revoked", slog.String("client_id", clientID), slog.Int64("count", revoked))
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE revoked_at IS NULL AND session_id IN (SELECT id FROM sessions WHERE client_id = $1)`,
+		clientID,
+	); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (o *oauthRepository) CreateAuthorizationCode(ctx context.Context, code models.AuthorizationCode) (err error) {
+	ctx, finish := startSpan(ctx, o.logger, "OAuthRepository.CreateAuthorizationCode", "INSERT")
+	defer finish(&err)
+
+	statement, err := o.db.PrepareContext(ctx, `
+		INSERT INTO oauth_authorization_codes (client_id, user_id, code_hash, redirect_uri, scopes, code_challenge, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx,
+		code.ClientID,
+		code.UserID,
+		code.CodeHash,
+		code.RedirectURI,
+		strings.Join(code.Scopes, " "),
+		code.CodeChallenge,
+		code.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *oauthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (_ models.AuthorizationCode, err error) {
+	ctx, finish := startSpan(ctx, o.logger, "OAuthRepository.ConsumeAuthorizationCode", "UPDATE")
+	defer finish(&err)
+
+	var code models.AuthorizationCode
+
+	row, err := o.db.QueryContext(ctx, `
+		UPDATE oauth_authorization_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE code_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING id, client_id, user_id, code_hash, redirect_uri, scopes, code_challenge, expires_at`,
+		codeHash, time.Now().UTC())
+	if err != nil {
+		return code, err
+	}
+	defer row.Close()
+
+	if row.Next() {
+		var scopes string
+		if err := row.Scan(
+			&code.ID,
+			&code.ClientID,
+			&code.UserID,
+			&code.CodeHash,
+			&code.RedirectURI,
+			&scopes,
+			&code.CodeChallenge,
+			&code.ExpiresAt,
+		); err != nil {
+			return code, err
+		}
+		code.Scopes = strings.Fields(scopes)
+	}
+
+	return code, nil
+}
+
+func scanOAuthClient(rows *sql.Rows, client *models.OAuthClient) error {
+	var redirectURIs, scopes string
+	if err := rows.Scan(
+		&client.ID,
+		&client.UserID,
+		&client.Name,
+		&client.SecretHash,
+		&redirectURIs,
+		&scopes,
+		&client.RevokedAt,
+		&client.CreatedAt,
+	); err != nil {
+		return err
+	}
+
+	client.Confidential = client.SecretHash != ""
+	client.RedirectURIs = strings.Fields(redirectURIs)
+	client.Scopes = strings.Fields(scopes)
+	return nil
+}
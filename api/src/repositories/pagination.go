@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"api/src/models"
+	"fmt"
+	"strings"
+)
+
+func paginate(query, where string, args []interface{}, page models.PageRequest, createdAtColumn, idColumn string) (string, []interface{}) {
+	var conditions []string
+	if where != "" {
+		conditions = append(conditions, where)
+	}
+
+	if page.After != nil {
+		args = append(args, page.After.CreatedAt, page.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, %s) < ($%d, $%d)", createdAtColumn, idColumn, len(args)-1, len(args)))
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, page.Limit+1)
+	query += fmt.Sprintf(" ORDER BY %s DESC, %s DESC LIMIT $%d", createdAtColumn, idColumn, len(args))
+
+	return query, args
+}
+
+func nextPage[T any](items []T, page models.PageRequest, cursor func(T) models.Cursor) ([]T, *models.Cursor) {
+	if len(items) <= page.Limit {
+		return items, nil
+	}
+
+	items = items[:page.Limit]
+	next := cursor(items[len(items)-1])
+	return items, &next
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"api/src/logging"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+type (
+	PasswordResetRepository interface {
+		CreatePasswordReset(ctx context.Context, userID uint64, tokenHash string, expiresAt time.Time) error
+		ConsumePasswordReset(ctx context.Context, tokenHash string) (uint64, error)
+	}
+
+	passwordResetRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewPasswordResetRepository(db *sql.DB, logger *slog.Logger) PasswordResetRepository {
+	return &passwordResetRepository{db: db, logger: logger}
+}
+
+func (p *passwordResetRepository) CreatePasswordReset(ctx context.Context, userID uint64, tokenHash string, expiresAt time.Time) (err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PasswordResetRepository.CreatePasswordReset", "UPDATE")
+	defer finish(&err)
+
+	statement, err := p.db.PrepareContext(ctx,
+		"UPDATE password_resets SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	result, err := statement.ExecContext(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if superseded, err := result.RowsAffected(); err == nil && superseded > 0 {
+		logging.FromContext(ctx, p.logger).DebugContext(ctx, "pending password resets superseded", slog.Uint64("user_id", userID), slog.Int64("count", superseded))
+	}
+
+	insert, err := p.db.PrepareContext(ctx,
+		"INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+	)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	if _, err = insert.ExecContext(ctx, userID, tokenHash, expiresAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *passwordResetRepository) ConsumePasswordReset(ctx context.Context, tokenHash string) (_ uint64, err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PasswordResetRepository.ConsumePasswordReset", "UPDATE")
+	defer finish(&err)
+
+	row, err := p.db.QueryContext(ctx, `
+		UPDATE password_resets SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING user_id`, tokenHash, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	defer row.Close()
+
+	var userID uint64
+	if row.Next() {
+		if err := row.Scan(&userID); err != nil {
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"api/src/models"
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type (
+	PersonalAccessTokenRepository interface {
+		CreateToken(ctx context.Context, token models.PersonalAccessToken) (uint64, error)
+		GetTokenByHash(ctx context.Context, tokenHash string) (models.PersonalAccessToken, error)
+		GetTokens(ctx context.Context, userID uint64) ([]models.PersonalAccessToken, error)
+		RevokeToken(ctx context.Context, userID, tokenID uint64) (bool, error)
+		TouchToken(ctx context.Context, tokenID uint64) error
+	}
+
+	personalAccessTokenRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewPersonalAccessTokenRepository(db *sql.DB, logger *slog.Logger) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{db: db, logger: logger}
+}
+
+func (p *personalAccessTokenRepository) CreateToken(ctx context.Context, token models.PersonalAccessToken) (_ uint64, err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PersonalAccessTokenRepository.CreateToken", "INSERT")
+	defer finish(&err)
+
+	statement, err := p.db.PrepareContext(ctx,
+		"INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer statement.Close()
+
+	var id uint64
+	err = statement.QueryRowContext(ctx,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		strings.Join(token.Scopes, " "),
+		token.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (p *personalAccessTokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (_ models.PersonalAccessToken, err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PersonalAccessTokenRepository.GetTokenByHash", "SELECT")
+	defer finish(&err)
+
+	var token models.PersonalAccessToken
+
+	row, err := p.db.QueryContext(ctx, `
+		SELECT id, user_id, name, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM personal_access_tokens
+		WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return token, err
+	}
+	defer row.Close()
+
+	if row.Next() {
+		if err := scanPersonalAccessToken(row, &token); err != nil {
+			return token, err
+		}
+	}
+
+	return token, nil
+}
+
+func (p *personalAccessTokenRepository) GetTokens(ctx context.Context, userID uint64) (_ []models.PersonalAccessToken, err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PersonalAccessTokenRepository.GetTokens", "SELECT")
+	defer finish(&err)
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, user_id, name, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM personal_access_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.PersonalAccessToken{}
+	for rows.Next() {
+		var token models.PersonalAccessToken
+		if err := scanPersonalAccessToken(rows, &token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (p *personalAccessTokenRepository) RevokeToken(ctx context.Context, userID, tokenID uint64) (_ bool, err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PersonalAccessTokenRepository.RevokeToken", "UPDATE")
+	defer finish(&err)
+
+	statement, err := p.db.PrepareContext(ctx,
+		"UPDATE personal_access_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+	)
+	if err != nil {
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.ExecContext(ctx, tokenID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (p *personalAccessTokenRepository) TouchToken(ctx context.Context, tokenID uint64) (err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PersonalAccessTokenRepository.TouchToken", "UPDATE")
+	defer finish(&err)
+
+	now := time.Now().UTC()
+
+	statement, err := p.db.PrepareContext(ctx,
+		"UPDATE personal_access_tokens SET last_used_at = $1 WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx, now, tokenID, now.Add(-time.Minute))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanPersonalAccessToken(rows *sql.Rows, token *models.PersonalAccessToken) error {
+	var scopes string
+	if err := rows.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&scopes,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	); err != nil {
+		return err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+	return nil
+}
@@ -1,33 +1,49 @@
 package repositories
 
 import (
+	"api/src/apperrors"
+	"api/src/logging"
 	"api/src/models"
+	"context"
 	"database/sql"
+	"log/slog"
+	"time"
 )
 
 type (
 	PublicationRepository interface {
-		CreatePublication(publication models.Publication) (uint64, error)
-		GetPublication(publicationID uint64) (models.Publication, error)
-		GetPublications(userID uint64) ([]models.Publication, error)
-		UpdatePublication(publicationID uint64, publication models.Publication) error
-		DeletePublication(publicationID uint64) error
-		FindByUser(userID uint64) ([]models.Publication, error)
-		Like(publicationID uint64) error
-		Unlike(publicationID uint64) error
+		CreatePublication(ctx context.Context, publication models.Publication) (uint64, error)
+		GetPublication(ctx context.Context, publicationID uint64) (models.Publication, error)
+		GetPublications(ctx context.Context, userID uint64, page models.PageRequest) ([]models.Publication, *models.Cursor, error)
+		UpdatePublication(ctx context.Context, publicationID uint64, publication models.Publication) error
+		DeletePublication(ctx context.Context, publicationID uint64) error
+		FindByUser(ctx context.Context, userID uint64, page models.PageRequest) ([]models.Publication, *models.Cursor, error)
+		Like(ctx context.Context, publicationID uint64) error
+		Unlike(ctx context.Context, publicationID uint64) error
 	}
 
 	publicationRepository struct {
-		db *sql.DB
+		db     *sql.DB
+		logger *slog.Logger
 	}
 )
 
-func NewPublicationRepository(db *sql.DB) PublicationRepository {
-	return &publicationRepository{db}
+const publicationColumns = `
+	SELECT p.id, p.title, p.content, p.author_id, p.likes, p.created_at, u.nick
+	FROM publications p
+	INNER JOIN users u ON u.id = p.author_id`
+
+var errPublicationNotFound = apperrors.NotFound("publication.not_found")
+
+func NewPublicationRepository(db *sql.DB, logger *slog.Logger) PublicationRepository {
+	return &publicationRepository{db: db, logger: logger}
 }
 
-func (p *publicationRepository) CreatePublication(publication models.Publication) (uint64, error) {
-	statement, err := p.db.Prepare(
+func (p *publicationRepository) CreatePublication(ctx context.Context, publication models.Publication) (_ uint64, err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PublicationRepository.CreatePublication", "INSERT")
+	defer finish(&err)
+
+	statement, err := p.db.PrepareContext(ctx,
 		"INSERT INTO publications (title, content, author_id) VALUES ($1, $2, $3) RETURNING id",
 	)
 	if err != nil {
@@ -36,18 +52,21 @@ func (p *publicationRepository) CreatePublication(publication models.Publication
 	defer statement.Close()
 
 	var lastInsertedID uint64
-	err = statement.QueryRow(publication.Title, publication.Content, publication.AuthorID).Scan(&lastInsertedID)
+	err = statement.QueryRowContext(ctx, publication.Title, publication.Content, publication.AuthorID).Scan(&lastInsertedID)
 	if err != nil {
-		return 0, err
+		return 0, translateError(err)
 	}
 
 	return lastInsertedID, nil
 }
 
-func (p *publicationRepository) GetPublication(publicationID uint64) (models.Publication, error) {
+func (p *publicationRepository) GetPublication(ctx context.Context, publicationID uint64) (_ models.Publication, err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PublicationRepository.GetPublication", "SELECT")
+	defer finish(&err)
+
 	var publication models.Publication
 
-	row, err := p.db.Query(`
+	row, err := p.db.QueryContext(ctx, `
 		SELECT p.id, p.title, p.content, p.author_id, p.likes, p.created_at, u.nick AS author_nick
 		FROM publications p
 		INNER JOIN users u ON u.id = p.author_id
@@ -69,93 +88,85 @@ func (p *publicationRepository) GetPublication(publicationID uint64) (models.Pub
 		); err != nil {
 			return publication, err
 		}
+		return publication, nil
 	}
-	return publication, nil
-}
-
-func (p *publicationRepository) GetPublications(userID uint64) ([]models.Publication, error) {
-	rows, err := p.db.Query(`
-		SELECT DISTINCT p.*, u.nick 
-		FROM publications p 
-		INNER JOIN users u ON u.id = p.author_id 
-		LEFT JOIN followers f ON p.author_id = f.user_id 
-		WHERE u.id = $1 OR f.follower_id = $2
-		ORDER BY p.id desc
-	`, userID, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var publications []models.Publication
-
-	for rows.Next() {
-		var publication models.Publication
 
-		if err := rows.Scan(
-			&publication.ID,
-			&publication.Title,
-			&publication.Content,
-			&publication.AuthorID,
-			&publication.Likes,
-			&publication.CreatedAt,
-			&publication.AuthorNick,
-		); err != nil {
-			return nil, err
-		}
+	return publication, errPublicationNotFound
+}
 
-		publications = append(publications, publication)
-	}
+func (p *publicationRepository) GetPublications(ctx context.Context, userID uint64, page models.PageRequest) (_ []models.Publication, _ *models.Cursor, err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PublicationRepository.GetPublications", "SELECT")
+	defer finish(&err)
+
+	query, args := paginate(
+		publicationColumns,
+		"(p.author_id = $1 OR p.author_id IN (SELECT user_id FROM followers WHERE follower_id = $1))",
+		[]interface{}{userID},
+		page,
+		"p.created_at",
+		"p.id",
+	)
 
-	return publications, nil
+	return p.queryPublications(ctx, query, args, page)
 }
 
-func (p *publicationRepository) UpdatePublication(publicationID uint64, publication models.Publication) error {
-	statement, err := p.db.Prepare("UPDATE publications SET title = $1, content = $2 WHERE id = $3")
+func (p *publicationRepository) UpdatePublication(ctx context.Context, publicationID uint64, publication models.Publication) (err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PublicationRepository.UpdatePublication", "UPDATE")
+	defer finish(&err)
+
+	statement, err := p.db.PrepareContext(ctx, "UPDATE publications SET title = $1, content = $2 WHERE id = $3")
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	if _, err = statement.Exec(publication.Title, publication.Content, publicationID); err != nil {
+	result, err := statement.ExecContext(ctx, publication.Title, publication.Content, publicationID)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return requireAffected(result, errPublicationNotFound)
 }
 
-func (p *publicationRepository) DeletePublication(publicationID uint64) error {
-	statement, err := p.db.Prepare("DELETE FROM publications WHERE id = $1")
+func (p *publicationRepository) DeletePublication(ctx context.Context, publicationID uint64) (err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PublicationRepository.DeletePublication", "DELETE")
+	defer finish(&err)
+
+	statement, err := p.db.PrepareContext(ctx, "DELETE FROM publications WHERE id = $1")
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	if _, err = statement.Exec(publicationID); err != nil {
+	result, err := statement.ExecContext(ctx, publicationID)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return requireAffected(result, errPublicationNotFound)
 }
 
-func (p *publicationRepository) FindByUser(userID uint64) ([]models.Publication, error) {
-	rows, err := p.db.Query(`
-        SELECT p.*, u.nick FROM publications p
-        JOIN users u ON u.id = p.author_id
-        WHERE p.author_id = $1`,
-		userID,
-	)
+func (p *publicationRepository) FindByUser(ctx context.Context, userID uint64, page models.PageRequest) (_ []models.Publication, _ *models.Cursor, err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PublicationRepository.FindByUser", "SELECT")
+	defer finish(&err)
+
+	query, args := paginate(publicationColumns, "p.author_id = $1", []interface{}{userID}, page, "p.created_at", "p.id")
+	return p.queryPublications(ctx, query, args, page)
+}
+
+func (p *publicationRepository) queryPublications(ctx context.Context, query string, args []interface{}, page models.PageRequest) ([]models.Publication, *models.Cursor, error) {
+	start := time.Now()
+	rows, err := p.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	var publications []models.Publication
-
+	publications := make([]models.Publication, 0, page.Limit+1)
 	for rows.Next() {
 		var publication models.Publication
 
-		if err = rows.Scan(
+		if err := rows.Scan(
 			&publication.ID,
 			&publication.Title,
 			&publication.Content,
@@ -164,31 +175,51 @@ func (p *publicationRepository) FindByUser(userID uint64) ([]models.Publication,
 			&publication.CreatedAt,
 			&publication.AuthorNick,
 		); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		publications = append(publications, publication)
 	}
 
-	return publications, nil
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	logging.FromContext(ctx, p.logger).DebugContext(ctx, "paginated query",
+		slog.String("query", query),
+		slog.Int("rows", len(publications)),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	publications, next := nextPage(publications, page, func(publication models.Publication) models.Cursor {
+		return models.Cursor{CreatedAt: publication.CreatedAt, ID: publication.ID}
+	})
+	return publications, next, nil
 }
 
-func (p *publicationRepository) Like(publicationID uint64) error {
-	statement, err := p.db.Prepare("UPDATE publications SET likes = likes + 1 WHERE id = $1")
+func (p *publicationRepository) Like(ctx context.Context, publicationID uint64) (err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PublicationRepository.Like", "UPDATE")
+	defer finish(&err)
+
+	statement, err := p.db.PrepareContext(ctx, "UPDATE publications SET likes = likes + 1 WHERE id = $1")
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	if _, err = statement.Exec(publicationID); err != nil {
+	result, err := statement.ExecContext(ctx, publicationID)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return requireAffected(result, errPublicationNotFound)
 }
 
-func (p *publicationRepository) Unlike(publicationID uint64) error {
-	statement, err := p.db.Prepare(`
+func (p *publicationRepository) Unlike(ctx context.Context, publicationID uint64) (err error) {
+	ctx, finish := startSpan(ctx, p.logger, "PublicationRepository.Unlike", "UPDATE")
+	defer finish(&err)
+
+	statement, err := p.db.PrepareContext(ctx, `
         UPDATE publications SET likes =
         CASE
             WHEN likes > 0 THEN likes - 1
@@ -199,10 +230,12 @@ func (p *publicationRepository) Unlike(publicationID uint64) error {
 	if err != nil {
 		return err
 	}
+	defer statement.Close()
 
-	if _, err = statement.Exec(publicationID); err != nil {
+	result, err := statement.ExecContext(ctx, publicationID)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return requireAffected(result, errPublicationNotFound)
 }
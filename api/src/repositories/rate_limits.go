@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"api/src/logging"
+	"api/src/ratelimit"
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const rateLimitSweepInterval = time.Minute
+
+type rateLimitRepository struct {
+	db        *sql.DB
+	logger    *slog.Logger
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewRateLimitRepository(db *sql.DB, logger *slog.Logger) ratelimit.Store {
+	return &rateLimitRepository{db: db, logger: logger, lastSweep: time.Now()}
+}
+
+func (l *rateLimitRepository) Take(ctx context.Context, key string, limit ratelimit.Limit) (_ ratelimit.Result, err error) {
+	ctx, finish := startSpan(ctx, l.logger, "RateLimitRepository.Take", "INSERT")
+	defer finish(&err)
+
+	now := time.Now().UTC()
+
+	if err := l.sweep(ctx, now); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO rate_limits (key, tokens, updated_at, expires_at) VALUES ($1, $2, $3, $3) ON CONFLICT (key) DO NOTHING",
+		key, float64(limit.Requests), now,
+	); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	var bucket ratelimit.Bucket
+	if err := tx.QueryRowContext(ctx,
+		"SELECT tokens, updated_at FROM rate_limits WHERE key = $1 FOR UPDATE", key,
+	).Scan(&bucket.Tokens, &bucket.UpdatedAt); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	bucket, result := limit.Take(bucket, now)
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE rate_limits SET tokens = $1, updated_at = $2, expires_at = $3 WHERE key = $4",
+		bucket.Tokens, bucket.UpdatedAt, now.Add(result.Reset), key,
+	); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	return result, tx.Commit()
+}
+
+func (l *rateLimitRepository) sweep(ctx context.Context, now time.Time) error {
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		l.mu.Unlock()
+		return nil
+	}
+	l.lastSweep = now
+	l.mu.Unlock()
+
+	result, err := l.db.ExecContext(ctx, "DELETE FROM rate_limits WHERE expires_at < $1", now)
+	if err != nil {
+		return err
+	}
+
+	if purged, err := result.RowsAffected(); err == nil && purged > 0 {
+		logging.FromContext(ctx, l.logger).DebugContext(ctx, "expired rate limit buckets purged", slog.Int64("count", purged))
+	}
+
+	return nil
+}
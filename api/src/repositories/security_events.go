@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"api/src/logging"
+	"api/src/models"
+	"context"
+	"database/sql"
+	"log/slog"
+)
+
+type (
+	SecurityEventRepository interface {
+		Record(ctx context.Context, event models.SecurityEvent) error
+	}
+
+	securityEventRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewSecurityEventRepository(db *sql.DB, logger *slog.Logger) SecurityEventRepository {
+	return &securityEventRepository{db: db, logger: logger}
+}
+
+func (s *securityEventRepository) Record(ctx context.Context, event models.SecurityEvent) (err error) {
+	ctx, finish := startSpan(ctx, s.logger, "SecurityEventRepository.Record", "INSERT")
+	defer finish(&err)
+
+	statement, err := s.db.PrepareContext(ctx,
+		"INSERT INTO security_events (user_id, type, ip, detail) VALUES (NULLIF($1, 0), $2, $3, $4)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx, int64(event.UserID), event.Type, event.IP, event.Detail)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, s.logger).DebugContext(ctx, "security event recorded", slog.String("type", event.Type), slog.Uint64("user_id", event.UserID))
+	return nil
+}
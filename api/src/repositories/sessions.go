@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"api/src/logging"
+	"api/src/models"
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type (
+	SessionRepository interface {
+		CreateSession(ctx context.Context, session models.Session) error
+		GetSession(ctx context.Context, sessionID string) (models.Session, error)
+		GetSessions(ctx context.Context, userID uint64) ([]models.Session, error)
+		TouchSession(ctx context.Context, sessionID string) error
+		RevokeSession(ctx context.Context, userID uint64, sessionID string) (bool, error)
+		RevokeOtherSessions(ctx context.Context, userID uint64, currentSessionID string) error
+	}
+
+	sessionRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewSessionRepository(db *sql.DB, logger *slog.Logger) SessionRepository {
+	return &sessionRepository{db: db, logger: logger}
+}
+
+func (s *sessionRepository) CreateSession(ctx context.Context, session models.Session) (err error) {
+	ctx, finish := startSpan(ctx, s.logger, "SessionRepository.CreateSession", "INSERT")
+	defer finish(&err)
+
+	statement, err := s.db.PrepareContext(ctx,
+		"INSERT INTO sessions (id, user_id, user_agent, ip, client_id, scopes, created_at, last_seen_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $7)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx,
+		session.ID,
+		session.UserID,
+		session.UserAgent,
+		session.IP,
+		sql.NullString{String: session.ClientID, Valid: session.ClientID != ""},
+		sql.NullString{String: strings.Join(session.Scopes, " "), Valid: len(session.Scopes) > 0},
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRepository) GetSession(ctx context.Context, sessionID string) (_ models.Session, err error) {
+	ctx, finish := startSpan(ctx, s.logger, "SessionRepository.GetSession", "SELECT")
+	defer finish(&err)
+
+	var session models.Session
+
+	row, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, COALESCE(user_agent, ''), COALESCE(ip, ''), COALESCE(client_id, ''), COALESCE(scopes, ''), created_at, last_seen_at, revoked_at
+		FROM sessions
+		WHERE id = $1`, sessionID)
+	if err != nil {
+		return session, err
+	}
+	defer row.Close()
+
+	if row.Next() {
+		if err := scanSession(row, &session); err != nil {
+			return session, err
+		}
+	}
+
+	return session, nil
+}
+
+func (s *sessionRepository) GetSessions(ctx context.Context, userID uint64) (_ []models.Session, err error) {
+	ctx, finish := startSpan(ctx, s.logger, "SessionRepository.GetSessions", "SELECT")
+	defer finish(&err)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, COALESCE(user_agent, ''), COALESCE(ip, ''), COALESCE(client_id, ''), COALESCE(scopes, ''), created_at, last_seen_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *sessionRepository) TouchSession(ctx context.Context, sessionID string) (err error) {
+	ctx, finish := startSpan(ctx, s.logger, "SessionRepository.TouchSession", "UPDATE")
+	defer finish(&err)
+
+	now := time.Now().UTC()
+
+	statement, err := s.db.PrepareContext(ctx,
+		"UPDATE sessions SET last_seen_at = $1 WHERE id = $2 AND last_seen_at < $3",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx, now, sessionID, now.Add(-time.Minute))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRepository) RevokeSession(ctx context.Context, userID uint64, sessionID string) (_ bool, err error) {
+	ctx, finish := startSpan(ctx, s.logger, "SessionRepository.RevokeSession", "UPDATE")
+	defer finish(&err)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		sessionID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if _, err = tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE session_id = $1 AND revoked_at IS NULL",
+		sessionID,
+	); err != nil {
+		return false, err
+	}
+
+	return affected == 1, tx.Commit()
+}
+
+func (s *sessionRepository) RevokeOtherSessions(ctx context.Context, userID uint64, currentSessionID string) (err error) {
+	ctx, finish := startSpan(ctx, s.logger, "SessionRepository.RevokeOtherSessions", "UPDATE")
+	defer finish(&err)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL",
+		userID, currentSessionID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if revoked, err := result.RowsAffected(); err == nil {
+		logging.FromContext(ctx, s.logger).DebugContext(ctx, "sessions revoked", slog.Uint64("user_id", userID), slog.Int64("count", revoked))
+	}
+
+	if _, err = tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND session_id <> $2 AND revoked_at IS NULL",
+		userID, currentSessionID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func scanSession(rows *sql.Rows, session *models.Session) error {
+	var scopes string
+	if err := rows.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IP,
+		&session.ClientID,
+		&scopes,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+	); err != nil {
+		return err
+	}
+
+	session.Scopes = strings.Fields(scopes)
+	return nil
+}
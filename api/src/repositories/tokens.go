@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"api/src/logging"
+	"api/src/models"
+	"context"
+	"database/sql"
+	"log/slog"
+)
+
+type (
+	TokenRepository interface {
+		CreateRefreshToken(ctx context.Context, token models.RefreshToken) error
+		GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+		MarkRefreshTokenUsed(ctx context.Context, id uint64) (bool, error)
+	}
+
+	tokenRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewTokenRepository(db *sql.DB, logger *slog.Logger) TokenRepository {
+	return &tokenRepository{db: db, logger: logger}
+}
+
+func (t *tokenRepository) CreateRefreshToken(ctx context.Context, token models.RefreshToken) (err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TokenRepository.CreateRefreshToken", "INSERT")
+	defer finish(&err)
+
+	statement, err := t.db.PrepareContext(ctx,
+		"INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.ExecContext(ctx, token.UserID, token.SessionID, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *tokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (_ models.RefreshToken, err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TokenRepository.GetRefreshToken", "SELECT")
+	defer finish(&err)
+
+	var token models.RefreshToken
+
+	row, err := t.db.QueryContext(ctx, `
+		SELECT id, user_id, session_id, token_hash, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return token, err
+	}
+	defer row.Close()
+
+	if row.Next() {
+		if err := row.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.SessionID,
+			&token.TokenHash,
+			&token.ExpiresAt,
+			&token.UsedAt,
+			&token.RevokedAt,
+			&token.CreatedAt,
+		); err != nil {
+			return token, err
+		}
+	}
+
+	return token, nil
+}
+
+func (t *tokenRepository) MarkRefreshTokenUsed(ctx context.Context, id uint64) (_ bool, err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TokenRepository.MarkRefreshTokenUsed", "UPDATE")
+	defer finish(&err)
+
+	statement, err := t.db.PrepareContext(ctx,
+		"UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL",
+	)
+	if err != nil {
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.ExecContext(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if affected == 0 {
+		logging.FromContext(ctx, t.logger).DebugContext(ctx, "refresh token already used or revoked", slog.Uint64("token_id", id))
+	}
+
+	return affected == 1, nil
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"api/src/apperrors"
+	"api/src/logging"
+	"api/src/tracing"
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+func startSpan(ctx context.Context, logger *slog.Logger, name, operation string) (context.Context, func(*error)) {
+	ctx, span := tracing.StartRepositorySpan(ctx, name, operation)
+
+	return ctx, func(err *error) {
+		defer span.End()
+
+		if *err == nil || errors.Is(*err, apperrors.ErrNotFound) {
+			return
+		}
+
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+		logging.FromContext(ctx, logger).DebugContext(ctx, "repository call failed",
+			slog.String("call", name),
+			slog.Any("error", *err),
+		)
+	}
+}
@@ -0,0 +1,280 @@
+package repositories
+
+import (
+	"api/src/logging"
+	"api/src/models"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+type (
+	TwoFactorRepository interface {
+		GetTwoFactor(ctx context.Context, userID uint64) (models.TwoFactor, error)
+		SetSecret(ctx context.Context, userID uint64, secret string) error
+		Enable(ctx context.Context, userID uint64, step int64, codeHashes []string) error
+		Disable(ctx context.Context, userID uint64) error
+		UseStep(ctx context.Context, userID uint64, step int64) (bool, error)
+		UseRecoveryCode(ctx context.Context, userID uint64, codeHash string) (bool, error)
+		CreateChallenge(ctx context.Context, challenge models.TwoFactorChallenge) error
+		ChallengeActive(ctx context.Context, challengeID string, userID uint64) (bool, error)
+		RecordChallengeFailure(ctx context.Context, challengeID string, maxFailures int) (int, error)
+		ConsumeChallenge(ctx context.Context, challengeID string) (bool, error)
+	}
+
+	twoFactorRepository struct {
+		db     *sql.DB
+		logger *slog.Logger
+	}
+)
+
+func NewTwoFactorRepository(db *sql.DB, logger *slog.Logger) TwoFactorRepository {
+	return &twoFactorRepository{db: db, logger: logger}
+}
+
+func (t *twoFactorRepository) GetTwoFactor(ctx context.Context, userID uint64) (_ models.TwoFactor, err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TwoFactorRepository.GetTwoFactor", "SELECT")
+	defer finish(&err)
+
+	var twoFactor models.TwoFactor
+
+	row, err := t.db.QueryContext(ctx,
+		"SELECT COALESCE(totp_secret, ''), totp_enabled_at, totp_last_step FROM users WHERE id = $1",
+		userID,
+	)
+	if err != nil {
+		return twoFactor, err
+	}
+	defer row.Close()
+
+	if row.Next() {
+		if err := row.Scan(&twoFactor.Secret, &twoFactor.EnabledAt, &twoFactor.LastStep); err != nil {
+			return twoFactor, err
+		}
+	}
+
+	return twoFactor, nil
+}
+
+func (t *twoFactorRepository) SetSecret(ctx context.Context, userID uint64, secret string) (err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TwoFactorRepository.SetSecret", "UPDATE")
+	defer finish(&err)
+
+	statement, err := t.db.PrepareContext(ctx,
+		"UPDATE users SET totp_secret = $1, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = $2",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.ExecContext(ctx, secret, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *twoFactorRepository) Enable(ctx context.Context, userID uint64, step int64, codeHashes []string) (err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TwoFactorRepository.Enable", "UPDATE")
+	defer finish(&err)
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx,
+		"UPDATE users SET totp_enabled_at = CURRENT_TIMESTAMP, totp_last_step = $1 WHERE id = $2",
+		step, userID,
+	); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	statement, err := tx.PrepareContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for _, codeHash := range codeHashes {
+		if _, err = statement.ExecContext(ctx, userID, codeHash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (t *twoFactorRepository) Disable(ctx context.Context, userID uint64) (err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TwoFactorRepository.Disable", "UPDATE")
+	defer finish(&err)
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx,
+		"UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = $1",
+		userID,
+	); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (t *twoFactorRepository) UseStep(ctx context.Context, userID uint64, step int64) (_ bool, err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TwoFactorRepository.UseStep", "UPDATE")
+	defer finish(&err)
+
+	statement, err := t.db.PrepareContext(ctx,
+		"UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1",
+	)
+	if err != nil {
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.ExecContext(ctx, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if affected == 0 {
+		logging.FromContext(ctx, t.logger).DebugContext(ctx, "TOTP step already used", slog.Uint64("user_id", userID), slog.Int64("step", step))
+	}
+
+	return affected == 1, nil
+}
+
+func (t *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID uint64, codeHash string) (_ bool, err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TwoFactorRepository.UseRecoveryCode", "UPDATE")
+	defer finish(&err)
+
+	statement, err := t.db.PrepareContext(ctx,
+		"UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL",
+	)
+	if err != nil {
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.ExecContext(ctx, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (t *twoFactorRepository) CreateChallenge(ctx context.Context, challenge models.TwoFactorChallenge) (err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TwoFactorRepository.CreateChallenge", "INSERT")
+	defer finish(&err)
+
+	statement, err := t.db.PrepareContext(ctx,
+		"INSERT INTO two_factor_challenges (id, user_id, expires_at) VALUES ($1, $2, $3)",
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.ExecContext(ctx, challenge.ID, challenge.UserID, challenge.ExpiresAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *twoFactorRepository) ChallengeActive(ctx context.Context, challengeID string, userID uint64) (_ bool, err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TwoFactorRepository.ChallengeActive", "SELECT")
+	defer finish(&err)
+
+	row, err := t.db.QueryContext(ctx,
+		"SELECT 1 FROM two_factor_challenges WHERE id = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > $3",
+		challengeID, userID, time.Now().UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	defer row.Close()
+
+	return row.Next(), row.Err()
+}
+
+func (t *twoFactorRepository) RecordChallengeFailure(ctx context.Context, challengeID string, maxFailures int) (_ int, err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TwoFactorRepository.RecordChallengeFailure", "UPDATE")
+	defer finish(&err)
+
+	row, err := t.db.QueryContext(ctx, `
+		UPDATE two_factor_challenges SET
+			failures = failures + 1,
+			used_at = CASE WHEN failures + 1 >= $2 THEN CURRENT_TIMESTAMP ELSE used_at END
+		WHERE id = $1 AND used_at IS NULL
+		RETURNING failures`, challengeID, maxFailures)
+	if err != nil {
+		return 0, err
+	}
+	defer row.Close()
+
+	var failures int
+	if row.Next() {
+		if err := row.Scan(&failures); err != nil {
+			return 0, err
+		}
+	}
+
+	if failures >= maxFailures {
+		logging.FromContext(ctx, t.logger).DebugContext(ctx, "two-factor challenge invalidated", slog.String("challenge_id", challengeID), slog.Int("failures", failures))
+	}
+
+	return failures, row.Err()
+}
+
+func (t *twoFactorRepository) ConsumeChallenge(ctx context.Context, challengeID string) (_ bool, err error) {
+	ctx, finish := startSpan(ctx, t.logger, "TwoFactorRepository.ConsumeChallenge", "UPDATE")
+	defer finish(&err)
+
+	statement, err := t.db.PrepareContext(ctx,
+		"UPDATE two_factor_challenges SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL AND expires_at > $2",
+	)
+	if err != nil {
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.ExecContext(ctx, challengeID, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
@@ -1,39 +1,53 @@
 package repositories
 
 import (
+	"api/src/apperrors"
+	"api/src/logging"
 	"api/src/models"
+	"context"
 	"database/sql"
+	"log/slog"
 	"time"
 )
 
 type (
 	UserRepository interface {
-		CreateUser(user models.User) (uint64, error)
-		GetUser(id uint64) (models.User, error)
-		GetUsers() ([]models.User, error)
-		GetUserByEmail(email string) (models.User, error)
-		UpdateUser(id uint64, user models.User) error
-		DeleteUser(id uint64) error
-		FollowUser(userID, followerID uint64) error
-		UnfollowUser(userID, followerID uint64) error
-		GetFollowers(userID uint64) ([]models.User, error)
-		GetFollowing(userID uint64) ([]models.User, error)
-		GetPassword(userID uint64) (string, error)
-		UpdatePassword(userID uint64, password string) error
+		CreateUser(ctx context.Context, user models.User) (uint64, error)
+		GetUser(ctx context.Context, id uint64) (models.User, error)
+		GetUsers(ctx context.Context, page models.PageRequest) ([]models.User, *models.Cursor, error)
+		GetUserByEmail(ctx context.Context, email string) (models.User, error)
+		UpdateUser(ctx context.Context, id uint64, user models.User) error
+		DeleteUser(ctx context.Context, id uint64) error
+		FollowUser(ctx context.Context, userID, followerID uint64) error
+		UnfollowUser(ctx context.Context, userID, followerID uint64) error
+		GetFollowers(ctx context.Context, userID uint64, page models.PageRequest) ([]models.User, *models.Cursor, error)
+		GetFollowing(ctx context.Context, userID uint64, page models.PageRequest) ([]models.User, *models.Cursor, error)
+		GetPassword(ctx context.Context, userID uint64) (string, error)
+		UpdatePassword(ctx context.Context, userID uint64, password string) error
+		ConfirmEmail(ctx context.Context, userID uint64, email string) error
+		IsEmailVerified(ctx context.Context, userID uint64) (bool, error)
+		UpdateRole(ctx context.Context, userID uint64, role string) error
+		GetLocale(ctx context.Context, userID uint64) (string, error)
 	}
 
 	userRepository struct {
-		db *sql.DB
+		db     *sql.DB
+		logger *slog.Logger
 	}
 )
 
-func NewUserRepository(db *sql.DB) UserRepository {
-	return &userRepository{db}
+var errUserNotFound = apperrors.NotFound("user.not_found")
+
+func NewUserRepository(db *sql.DB, logger *slog.Logger) UserRepository {
+	return &userRepository{db: db, logger: logger}
 }
 
-func (u *userRepository) CreateUser(user models.User) (uint64, error) {
-	statement, err := u.db.Prepare(
-		"INSERT INTO users (name, nick, email, password) VALUES ($1, $2, $3, $4) RETURNING id",
+func (u *userRepository) CreateUser(ctx context.Context, user models.User) (_ uint64, err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.CreateUser", "INSERT")
+	defer finish(&err)
+
+	statement, err := u.db.PrepareContext(ctx,
+		"INSERT INTO users (name, nick, email, password, locale) VALUES ($1, $2, $3, $4, NULLIF($5, '')) RETURNING id",
 	)
 	if err != nil {
 		return 0, err
@@ -41,64 +55,51 @@ func (u *userRepository) CreateUser(user models.User) (uint64, error) {
 	defer statement.Close()
 
 	var id uint64
-	err = statement.QueryRow(user.Name, user.Nick, user.Email, user.Password).Scan(&id)
+	err = statement.QueryRowContext(ctx, user.Name, user.Nick, user.Email, user.Password, user.Locale).Scan(&id)
 	if err != nil {
-		return 0, err
+		return 0, translateError(err)
 	}
 
 	return id, nil
 }
 
-func (u *userRepository) GetUser(id uint64) (models.User, error) {
+func (u *userRepository) GetUser(ctx context.Context, id uint64) (_ models.User, err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.GetUser", "SELECT")
+	defer finish(&err)
+
 	var user models.User
 
-	row, err := u.db.Query("SELECT id, name, nick, email FROM users WHERE id = $1", id)
+	row, err := u.db.QueryContext(ctx, "SELECT id, name, nick, email, email_verified_at, role, COALESCE(locale, '') FROM users WHERE id = $1", id)
 	if err != nil {
 		return user, err
 	}
 	defer row.Close()
 
 	if row.Next() {
-		if err := row.Scan(&user.ID, &user.Name, &user.Nick, &user.Email); err != nil {
+		if err := row.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.EmailVerifiedAt, &user.Role, &user.Locale); err != nil {
 			return user, err
 		}
+		return user, nil
 	}
 
-	return user, nil
+	return user, errUserNotFound
 }
 
-func (u *userRepository) GetUsers() ([]models.User, error) {
-	rows, err := u.db.Query("SELECT id, name, nick, email FROM users")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+func (u *userRepository) GetUsers(ctx context.Context, page models.PageRequest) (_ []models.User, _ *models.Cursor, err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.GetUsers", "SELECT")
+	defer finish(&err)
 
-	var users []models.User
-	for rows.Next() {
-		var id uint64
-		var name, nick, email string
-		if err := rows.Scan(&id, &name, &nick, &email); err != nil {
-			return nil, err
-		}
-		users = append(users, models.User{
-			ID:    id,
-			Name:  name,
-			Nick:  nick,
-			Email: email,
-		})
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return users, nil
+	query, args := paginate("SELECT id, name, nick, email, created_at FROM users", "", nil, page, "created_at", "id")
+	return u.queryUsers(ctx, query, args, page)
 }
 
-func (u *userRepository) GetUserByEmail(email string) (models.User, error) {
+func (u *userRepository) GetUserByEmail(ctx context.Context, email string) (_ models.User, err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.GetUserByEmail", "SELECT")
+	defer finish(&err)
+
 	var user models.User
 
-	row, err := u.db.Query("SELECT id, password FROM users WHERE email = $1", email)
+	row, err := u.db.QueryContext(ctx, "SELECT id, password FROM users WHERE email = $1", email)
 	if err != nil {
 		return user, err
 	}
@@ -108,67 +109,81 @@ func (u *userRepository) GetUserByEmail(email string) (models.User, error) {
 		if err := row.Scan(&user.ID, &user.Password); err != nil {
 			return user, err
 		}
+		return user, nil
 	}
-	return user, nil
+
+	return user, errUserNotFound
 
 }
 
-func (u *userRepository) UpdateUser(id uint64, user models.User) error {
-	statement, err := u.db.Prepare(
-		"UPDATE users SET name = $1, nick = $2, email = $3 WHERE id = $4",
+func (u *userRepository) UpdateUser(ctx context.Context, id uint64, user models.User) (err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.UpdateUser", "UPDATE")
+	defer finish(&err)
+
+	statement, err := u.db.PrepareContext(ctx,
+		"UPDATE users SET name = $1, nick = $2, email = $3, locale = COALESCE(NULLIF($4, ''), locale) WHERE id = $5",
 	)
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	_, err = statement.Exec(user.Name, user.Nick, user.Email, id)
+	result, err := statement.ExecContext(ctx, user.Name, user.Nick, user.Email, user.Locale, id)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 
-	return nil
+	return requireAffected(result, errUserNotFound)
 }
 
-func (u *userRepository) DeleteUser(id uint64) error {
-	statement, err := u.db.Prepare("DELETE FROM users WHERE id = $1")
+func (u *userRepository) DeleteUser(ctx context.Context, id uint64) (err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.DeleteUser", "DELETE")
+	defer finish(&err)
+
+	statement, err := u.db.PrepareContext(ctx, "DELETE FROM users WHERE id = $1")
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	_, err = statement.Exec(id)
+	result, err := statement.ExecContext(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return requireAffected(result, errUserNotFound)
 }
 
-func (u *userRepository) FollowUser(userID, followerID uint64) error {
-	statement, err := u.db.Prepare("INSERT INTO followers (user_id, follower_id) VALUES ($1, $2) ON CONFLICT DO NOTHING")
+func (u *userRepository) FollowUser(ctx context.Context, userID, followerID uint64) (err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.FollowUser", "INSERT")
+	defer finish(&err)
+
+	statement, err := u.db.PrepareContext(ctx, "INSERT INTO followers (user_id, follower_id) VALUES ($1, $2) ON CONFLICT DO NOTHING")
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	_, err = statement.Exec(userID, followerID)
+	_, err = statement.ExecContext(ctx, userID, followerID)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 
 	return nil
 
 }
 
-func (u *userRepository) UnfollowUser(userID, followerID uint64) error {
-	statement, err := u.db.Prepare("DELETE FROM followers WHERE user_id = $1 AND follower_id = $2")
+func (u *userRepository) UnfollowUser(ctx context.Context, userID, followerID uint64) (err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.UnfollowUser", "DELETE")
+	defer finish(&err)
+
+	statement, err := u.db.PrepareContext(ctx, "DELETE FROM followers WHERE user_id = $1 AND follower_id = $2")
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	_, err = statement.Exec(userID, followerID)
+	_, err = statement.ExecContext(ctx, userID, followerID)
 	if err != nil {
 		return err
 	}
@@ -177,95 +192,178 @@ func (u *userRepository) UnfollowUser(userID, followerID uint64) error {
 
 }
 
-func (u *userRepository) GetFollowers(userID uint64) ([]models.User, error) {
-	rows, err := u.db.Query("SELECT u.id, u.name, u.nick, u.email, u.created_at FROM users u INNER JOIN followers f ON u.id = f.follower_id WHERE f.user_id = $1", userID)
+func (u *userRepository) GetFollowers(ctx context.Context, userID uint64, page models.PageRequest) (_ []models.User, _ *models.Cursor, err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.GetFollowers", "SELECT")
+	defer finish(&err)
+
+	query, args := paginate(
+		"SELECT u.id, u.name, u.nick, u.email, f.created_at FROM users u INNER JOIN followers f ON u.id = f.follower_id",
+		"f.user_id = $1",
+		[]interface{}{userID},
+		page,
+		"f.created_at",
+		"f.follower_id",
+	)
+	return u.queryUsers(ctx, query, args, page)
+}
+
+func (u *userRepository) GetFollowing(ctx context.Context, userID uint64, page models.PageRequest) (_ []models.User, _ *models.Cursor, err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.GetFollowing", "SELECT")
+	defer finish(&err)
+
+	query, args := paginate(
+		"SELECT u.id, u.name, u.nick, u.email, f.created_at FROM users u INNER JOIN followers f ON u.id = f.user_id",
+		"f.follower_id = $1",
+		[]interface{}{userID},
+		page,
+		"f.created_at",
+		"f.user_id",
+	)
+	return u.queryUsers(ctx, query, args, page)
+}
+
+func (u *userRepository) queryUsers(ctx context.Context, query string, args []interface{}, page models.PageRequest) ([]models.User, *models.Cursor, error) {
+	start := time.Now()
+	rows, err := u.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	var followers []models.User
+	users := make([]models.User, 0, page.Limit+1)
 	for rows.Next() {
-		var id uint64
-		var name, nick, email string
-		var createdAt time.Time
-		if err := rows.Scan(&id, &name, &nick, &email, &createdAt); err != nil {
-			return nil, err
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.CreatedAt); err != nil {
+			return nil, nil, err
 		}
-		followers = append(followers, models.User{
-			ID:        id,
-			Name:      name,
-			Nick:      nick,
-			Email:     email,
-			CreatedAt: createdAt,
-		})
+		users = append(users, user)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return followers, nil
+	logging.FromContext(ctx, u.logger).DebugContext(ctx, "paginated query",
+		slog.String("query", query),
+		slog.Int("rows", len(users)),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	users, next := nextPage(users, page, func(user models.User) models.Cursor {
+		return models.Cursor{CreatedAt: user.CreatedAt, ID: user.ID}
+	})
+	return users, next, nil
 }
 
-func (u *userRepository) GetFollowing(userID uint64) ([]models.User, error) {
-	rows, err := u.db.Query("SELECT u.id, u.name, u.nick, u.email, u.created_at FROM users u INNER JOIN followers f ON u.id = f.user_id WHERE f.follower_id = $1", userID)
+func (u *userRepository) GetPassword(ctx context.Context, userID uint64) (_ string, err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.GetPassword", "SELECT")
+	defer finish(&err)
+
+	row, err := u.db.QueryContext(ctx, "SELECT password FROM users WHERE id = $1", userID)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	defer rows.Close()
+	defer row.Close()
 
-	var followers []models.User
-	for rows.Next() {
-		var id uint64
-		var name, nick, email string
-		var createdAt time.Time
-		if err := rows.Scan(&id, &name, &nick, &email, &createdAt); err != nil {
-			return nil, err
+	var password string
+	if row.Next() {
+		if err := row.Scan(&password); err != nil {
+			return "", err
 		}
-		followers = append(followers, models.User{
-			ID:        id,
-			Name:      name,
-			Nick:      nick,
-			Email:     email,
-			CreatedAt: createdAt,
-		})
+		return password, nil
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return "", errUserNotFound
+}
+
+func (u *userRepository) UpdatePassword(ctx context.Context, userID uint64, password string) (err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.UpdatePassword", "UPDATE")
+	defer finish(&err)
+
+	statement, err := u.db.PrepareContext(ctx, "UPDATE users SET password = $1 WHERE id = $2")
+	if err != nil {
+		return err
 	}
+	defer statement.Close()
 
-	return followers, nil
+	result, err := statement.ExecContext(ctx, password, userID)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, errUserNotFound)
 }
 
-func (u *userRepository) GetPassword(userID uint64) (string, error) {
-	row, err := u.db.Query("SELECT password FROM users WHERE id = $1", userID)
+func (u *userRepository) ConfirmEmail(ctx context.Context, userID uint64, email string) (err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.ConfirmEmail", "UPDATE")
+	defer finish(&err)
+
+	statement, err := u.db.PrepareContext(ctx, "UPDATE users SET email = $1, email_verified_at = CURRENT_TIMESTAMP WHERE id = $2")
 	if err != nil {
-		return "", err
+		return err
+	}
+	defer statement.Close()
+
+	result, err := statement.ExecContext(ctx, email, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	return requireAffected(result, errUserNotFound)
+}
+
+func (u *userRepository) IsEmailVerified(ctx context.Context, userID uint64) (_ bool, err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.IsEmailVerified", "SELECT")
+	defer finish(&err)
+
+	row, err := u.db.QueryContext(ctx, "SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1", userID)
+	if err != nil {
+		return false, err
 	}
 	defer row.Close()
 
-	var password string
+	var verified bool
 	if row.Next() {
-		if err := row.Scan(&password); err != nil {
-			return "", err
+		if err := row.Scan(&verified); err != nil {
+			return false, err
 		}
 	}
-	return password, nil
+	return verified, nil
 }
 
-func (u *userRepository) UpdatePassword(userID uint64, password string) error {
-	statement, err := u.db.Prepare("UPDATE users SET password = $1 WHERE id = $2")
+func (u *userRepository) UpdateRole(ctx context.Context, userID uint64, role string) (err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.UpdateRole", "UPDATE")
+	defer finish(&err)
+
+	statement, err := u.db.PrepareContext(ctx, "UPDATE users SET role = $1 WHERE id = $2")
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
-	_, err = statement.Exec(password, userID)
+	result, err := statement.ExecContext(ctx, role, userID)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 
-	return nil
+	return requireAffected(result, errUserNotFound)
+}
+
+func (u *userRepository) GetLocale(ctx context.Context, userID uint64) (_ string, err error) {
+	ctx, finish := startSpan(ctx, u.logger, "UserRepository.GetLocale", "SELECT")
+	defer finish(&err)
+
+	row, err := u.db.QueryContext(ctx, "SELECT COALESCE(locale, '') FROM users WHERE id = $1", userID)
+	if err != nil {
+		return "", err
+	}
+	defer row.Close()
+
+	var locale string
+	if row.Next() {
+		if err := row.Scan(&locale); err != nil {
+			return "", err
+		}
+	}
+	return locale, nil
 }
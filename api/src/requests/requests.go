@@ -0,0 +1,74 @@
+package requests
+
+import (
+	"api/src/apperrors"
+	"api/src/config"
+	"api/src/models"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !trustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		ip = hop
+		if !trustedProxy(hop) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func trustedProxy(value string) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range config.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func Page(r *http.Request) (models.PageRequest, error) {
+	page := models.PageRequest{Limit: config.PageDefaultLimit}
+	query := r.URL.Query()
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return page, apperrors.Invalid(apperrors.Field("limit", "invalid", "page.limit_invalid"))
+		}
+		page.Limit = min(limit, config.PageMaxLimit)
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := models.DecodeCursor(value)
+		if err != nil {
+			return page, err
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
@@ -1,9 +1,21 @@
 package responses
 
 import (
+	"api/src/apperrors"
+	"api/src/config"
+	"api/src/i18n"
+	"api/src/logging"
+	"api/src/models"
+	"api/src/requests"
+	"api/src/tracing"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
@@ -12,16 +24,128 @@ func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
 
 	if data != nil {
 		if err := json.NewEncoder(w).Encode(data); err != nil {
-			log.Fatal(err)
+			slog.Error("failed to encode response", slog.Any("error", err))
 		}
 	}
+}
+
+func Page(w http.ResponseWriter, r *http.Request, items interface{}, next *models.Cursor) {
+	if next != nil {
+		cursor := next.Encode()
+
+		query := r.URL.Query()
+		query.Set("cursor", cursor)
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, config.AppURL, r.URL.Path, query.Encode()))
+		w.Header().Set("X-Next-Cursor", cursor)
+	}
+
+	JSON(w, http.StatusOK, items)
+}
+
+const (
+	problemContentType = "application/problem+json"
+	legacyContentType  = "application/vnd.devbook.legacy+json"
+)
+
+type Problem struct {
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Status    int                    `json:"status"`
+	Detail    string                 `json:"detail,omitempty"`
+	Instance  string                 `json:"instance,omitempty"`
+	RequestID string                 `json:"requestId,omitempty"`
+	TraceID   string                 `json:"traceId,omitempty"`
+	Errors    []apperrors.FieldError `json:"errors,omitempty"`
+}
+
+func Err(w http.ResponseWriter, r *http.Request, statusCode int, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		statusCode = http.StatusRequestEntityTooLarge
+		err = apperrors.New("request.too_large", tooLarge.Limit)
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		logging.FromContext(r.Context(), slog.Default()).ErrorContext(r.Context(), "request failed", slog.Any("error", err))
+		trace.SpanFromContext(r.Context()).RecordError(err)
+	}
+
+	locale := i18n.RequestLocale(r)
+	message, fields := apperrors.Localize(err, locale)
+	w.Header().Set("Content-Language", locale)
+
+	if !wantsProblem(r) {
+		JSON(w, statusCode, struct {
+			Err string `json:"err"`
+		}{
+			Err: message,
+		})
+		return
+	}
+
+	problem := Problem{
+		Type:      problemType(err),
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Detail:    message,
+		Instance:  r.URL.Path,
+		RequestID: requests.ID(r.Context()),
+		TraceID:   tracing.TraceID(r.Context()),
+		Errors:    fields,
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		logging.FromContext(r.Context(), slog.Default()).ErrorContext(r.Context(), "failed to encode problem response", slog.Any("error", err))
+	}
+}
+
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	Err(w, r, StatusCode(err), err)
+}
+
+func wantsProblem(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, problemContentType):
+		return true
+	case strings.Contains(accept, legacyContentType):
+		return false
+	default:
+		return config.ErrorFormat != "legacy"
+	}
+}
+
+func problemType(err error) string {
+	var slug string
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		slug = "not-found"
+	case errors.Is(err, apperrors.ErrConflict):
+		slug = "conflict"
+	case errors.Is(err, apperrors.ErrForbidden):
+		slug = "forbidden"
+	case errors.Is(err, apperrors.ErrValidation):
+		slug = "validation-error"
+	default:
+		return "about:blank"
+	}
 
+	return config.AppURL + "/problems/" + slug
 }
 
-func Err(w http.ResponseWriter, statusCode int, err error) {
-	JSON(w, statusCode, struct {
-		Err string `json:"err"`
-	}{
-		Err: err.Error(),
-	})
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperrors.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperrors.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apperrors.ErrValidation):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
 }
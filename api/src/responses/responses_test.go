@@ -0,0 +1,46 @@
+package responses
+
+import (
+	"api/src/config"
+	"api/src/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPageKeepsArrayBodyAndReturnsCursorInHeaders(t *testing.T) {
+	config.AppURL = "https://api.example.com"
+	next := &models.Cursor{CreatedAt: time.Date(2024, 5, 17, 10, 30, 0, 0, time.UTC), ID: 7}
+
+	recorder := httptest.NewRecorder()
+	Page(recorder, httptest.NewRequest(http.MethodGet, "/users?limit=2", nil), []string{"a", "b"}, next)
+
+	if body := strings.TrimSpace(recorder.Body.String()); body != `["a","b"]` {
+		t.Fatalf("body %s is not the bare array", body)
+	}
+
+	if cursor := recorder.Header().Get("X-Next-Cursor"); cursor != next.Encode() {
+		t.Errorf("X-Next-Cursor %q, want %q", cursor, next.Encode())
+	}
+
+	query := url.Values{"limit": {"2"}, "cursor": {next.Encode()}}
+	if link := recorder.Header().Get("Link"); link != `<https://api.example.com/users?`+query.Encode()+`>; rel="next"` {
+		t.Errorf("unexpected Link header %q", link)
+	}
+}
+
+func TestPageWithoutNextCursor(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Page(recorder, httptest.NewRequest(http.MethodGet, "/users", nil), []string{}, nil)
+
+	if body := strings.TrimSpace(recorder.Body.String()); body != `[]` {
+		t.Fatalf("body %s is not an empty array", body)
+	}
+
+	if recorder.Header().Get("X-Next-Cursor") != "" || recorder.Header().Get("Link") != "" {
+		t.Error("last page advertises a next cursor")
+	}
+}
@@ -1,13 +1,13 @@
 package router
 
 import (
-	"api/src/controllers"
 	"api/src/router/routes"
+	"api/src/server/services"
 
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(authContoller *controllers.AuthController, userController *controllers.UserController, publicationController *controllers.PublicationController) *mux.Router {
+func NewRouter(s *services.Services) *mux.Router {
 	r := mux.NewRouter()
-	return routes.Configure(r, authContoller, userController, publicationController)
+	return routes.Configure(r, s)
 }
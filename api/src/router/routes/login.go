@@ -1,10 +1,14 @@
 package routes
 
 import (
+	"api/src/authentication"
+	"api/src/config"
 	"api/src/controllers"
 	"net/http"
 )
 
+const credentialsMaxBodyBytes = 16 << 10
+
 func AuthRoutes(authController *controllers.AuthController) []Route {
 	return []Route{
 		{
@@ -12,6 +16,68 @@ func AuthRoutes(authController *controllers.AuthController) []Route {
 			Method:         http.MethodPost,
 			Function:       authController.Login,
 			Authentication: false,
+			RateLimit:      config.RateLimitAuth,
+			MaxBodyBytes:   credentialsMaxBodyBytes,
+		},
+		{
+			URI:            "/login/mfa",
+			Method:         http.MethodPost,
+			Function:       authController.LoginMFA,
+			Authentication: false,
+			RateLimit:      config.RateLimitAuth,
+			MaxBodyBytes:   credentialsMaxBodyBytes,
+		},
+		{
+			URI:            "/login/magic",
+			Method:         http.MethodPost,
+			Function:       authController.RequestMagicLink,
+			Authentication: false,
+			RateLimit:      config.RateLimitAuth,
+			MaxBodyBytes:   credentialsMaxBodyBytes,
+		},
+		{
+			URI:            "/login/magic/verify",
+			Method:         http.MethodPost,
+			Function:       authController.VerifyMagicLink,
+			Authentication: false,
+			RateLimit:      config.RateLimitAuth,
+			MaxBodyBytes:   credentialsMaxBodyBytes,
+		},
+		{
+			URI:            "/token/refresh",
+			Method:         http.MethodPost,
+			Function:       authController.RefreshToken,
+			Authentication: false,
+			MaxBodyBytes:   credentialsMaxBodyBytes,
+		},
+		{
+			URI:            "/logout",
+			Method:         http.MethodPost,
+			Function:       authController.Logout,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+		},
+		{
+			URI:            "/password/forgot",
+			Method:         http.MethodPost,
+			Function:       authController.ForgotPassword,
+			Authentication: false,
+			RateLimit:      config.RateLimitAuth,
+			MaxBodyBytes:   credentialsMaxBodyBytes,
+		},
+		{
+			URI:            "/password/reset",
+			Method:         http.MethodPost,
+			Function:       authController.ResetPassword,
+			Authentication: false,
+			RateLimit:      config.RateLimitAuth,
+			MaxBodyBytes:   credentialsMaxBodyBytes,
+		},
+		{
+			URI:            "/.well-known/jwks.json",
+			Method:         http.MethodGet,
+			Function:       authController.JWKS,
+			Authentication: false,
 		},
 	}
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"api/src/metrics"
+	"net/http"
+)
+
+func MetricsRoutes(metrics *metrics.Metrics) []Route {
+	return []Route{
+		{
+			URI:            "/metrics",
+			Method:         http.MethodGet,
+			Function:       metrics.Handler,
+			Authentication: false,
+		},
+	}
+}
@@ -0,0 +1,77 @@
+package routes
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/controllers"
+	"api/src/middlewares"
+	"net/http"
+)
+
+func OAuthRoutes(oauthController *controllers.OAuthController) []Route {
+	return []Route{
+		{
+			URI:            "/users/{id}/oauth/clients",
+			Method:         http.MethodPost,
+			Function:       oauthController.CreateClient,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+		{
+			URI:            "/users/{id}/oauth/clients",
+			Method:         http.MethodGet,
+			Function:       oauthController.GetClients,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+		{
+			URI:            "/users/{id}/oauth/clients/{clientId}",
+			Method:         http.MethodDelete,
+			Function:       oauthController.RevokeClient,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+		{
+			URI:            "/oauth/authorize",
+			Method:         http.MethodGet,
+			Function:       oauthController.GetConsent,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+		},
+		{
+			URI:            "/oauth/authorize",
+			Method:         http.MethodPost,
+			Function:       oauthController.Authorize,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+		},
+		{
+			URI:            "/oauth/token",
+			Method:         http.MethodPost,
+			Function:       oauthController.Token,
+			Authentication: false,
+			RateLimit:      config.RateLimitAuth,
+		},
+		{
+			URI:            "/oauth/introspect",
+			Method:         http.MethodPost,
+			Function:       oauthController.Introspect,
+			Authentication: false,
+		},
+		{
+			URI:            "/oauth/revoke",
+			Method:         http.MethodPost,
+			Function:       oauthController.Revoke,
+			Authentication: false,
+		},
+		{
+			URI:            "/.well-known/oauth-authorization-server",
+			Method:         http.MethodGet,
+			Function:       oauthController.Metadata,
+			Authentication: false,
+		},
+	}
+}
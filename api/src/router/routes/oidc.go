@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+func OIDCRoutes(oidcController *controllers.OIDCController) []Route {
+	return []Route{
+		{
+			URI:            "/login/oidc/{provider}",
+			Method:         http.MethodGet,
+			Function:       oidcController.Begin,
+			Authentication: false,
+		},
+		{
+			URI:            "/login/oidc/{provider}/callback",
+			Method:         http.MethodGet,
+			Function:       oidcController.Callback,
+			Authentication: false,
+		},
+	}
+}
@@ -1,7 +1,10 @@
 package routes
 
 import (
+	"api/src/authentication"
+	"api/src/config"
 	"api/src/controllers"
+	"api/src/middlewares"
 	"net/http"
 )
 
@@ -12,48 +15,60 @@ func PublicationRoutes(publicationController *controllers.PublicationController)
 			Method:         http.MethodPost,
 			Function:       publicationController.CreatePublication,
 			Authentication: true,
+			Scope:          authentication.ScopePublicationsWrite,
+			RateLimit:      config.RateLimitPublish,
 		},
 		{
 			URI:            "/publications",
 			Method:         http.MethodGet,
 			Function:       publicationController.GetPublications,
 			Authentication: true,
+			Scope:          authentication.ScopePublicationsRead,
 		},
 		{
 			URI:            "/publications/{publicationId}",
 			Method:         http.MethodGet,
 			Function:       publicationController.GetPublication,
 			Authentication: true,
+			Scope:          authentication.ScopePublicationsRead,
 		},
 		{
 			URI:            "/publications/{publicationId}",
 			Method:         http.MethodPut,
 			Function:       publicationController.UpdatePublication,
 			Authentication: true,
+			Scope:          authentication.ScopePublicationsWrite,
+			Owner:          middlewares.OwnedByPublicationAuthor,
 		},
 		{
 			URI:            "/publications/{publicationId}",
 			Method:         http.MethodDelete,
 			Function:       publicationController.DeletePublication,
 			Authentication: true,
+			Scope:          authentication.ScopePublicationsWrite,
+			Permission:     authentication.PermissionModeratePublications,
+			Owner:          middlewares.OwnedByPublicationAuthor,
 		},
 		{
 			URI:            "/users/{userId}/publications",
 			Method:         http.MethodGet,
 			Function:       publicationController.SearchPublicationsByUser,
 			Authentication: true,
+			Scope:          authentication.ScopePublicationsRead,
 		},
 		{
 			URI:            "/publications/{publicationId}/like",
 			Method:         http.MethodPost,
 			Function:       publicationController.LikePublication,
 			Authentication: true,
+			Scope:          authentication.ScopePublicationsWrite,
 		},
 		{
 			URI:            "/publications/{publicationId}/unlike",
 			Method:         http.MethodPost,
 			Function:       publicationController.UnlikePublication,
 			Authentication: true,
+			Scope:          authentication.ScopePublicationsWrite,
 		},
 	}
 }
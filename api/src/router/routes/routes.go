@@ -1,8 +1,11 @@
 package routes
 
 import (
-	"api/src/controllers"
+	"api/src/authentication"
+	"api/src/config"
 	"api/src/middlewares"
+	"api/src/ratelimit"
+	"api/src/server/services"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -13,25 +16,65 @@ type Route struct {
 	Method         string
 	Function       func(http.ResponseWriter, *http.Request)
 	Authentication bool
+	Scope          string
+	Permission     authentication.Permission
+	Owner          middlewares.Ownership
+	RateLimit      ratelimit.Limit
+	MaxBodyBytes   int64
 }
 
-func Configure(r *mux.Router, authContoller *controllers.AuthController, userController *controllers.UserController, publicationController *controllers.PublicationController) *mux.Router {
+func Configure(r *mux.Router, s *services.Services) *mux.Router {
+	r.Use(middlewares.SecurityHeaders, middlewares.CORS)
+	r.Methods(http.MethodOptions).HandlerFunc(middlewares.Preflight)
+
+	r.HandleFunc("/healthz", s.HealthController.Healthz).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", s.HealthController.Readyz).Methods(http.MethodGet)
+	r.HandleFunc("/debug/info", s.HealthController.Info).Methods(http.MethodGet)
+
 	allRoutes := [][]Route{
-		UserRoutes(userController),
-		AuthRoutes(authContoller),
-		PublicationRoutes(publicationController),
+		UserRoutes(s.UserController),
+		AuthRoutes(s.AuthController),
+		PublicationRoutes(s.PublicationController),
+		TwoFactorRoutes(s.TwoFactorController),
+		TokenRoutes(s.TokenController),
+		SessionRoutes(s.SessionController),
+		OIDCRoutes(s.OIDCController),
+		OAuthRoutes(s.OAuthController),
+		MetricsRoutes(s.Metrics),
 	}
 
 	for _, routes := range allRoutes {
 		for _, route := range routes {
+			limit := route.RateLimit
+			if !limit.Enabled() {
+				limit = config.RateLimitDefault
+			}
 
+			maxBodyBytes := route.MaxBodyBytes
+			if maxBodyBytes <= 0 {
+				maxBodyBytes = config.MaxBodyBytes
+			}
+
+			handler := s.Middlewares.RateLimit(route.URI, route.Method, limit, route.Function)
 			if route.Authentication {
-				r.HandleFunc(route.URI,
-					middlewares.Logger(middlewares.Authenticate(route.Function)),
-				).Methods(route.Method)
-			} else {
-				r.HandleFunc(route.URI, middlewares.Logger(route.Function)).Methods(route.Method)
+				handler = s.Middlewares.RateLimitIP(config.RateLimitIP,
+					s.Middlewares.Authenticate(
+						s.Middlewares.RateLimit(route.URI, route.Method, limit,
+							s.Middlewares.Authorize(route.Scope,
+								s.Middlewares.RequirePermission(route.Permission, route.Owner, route.Function),
+							),
+						),
+					),
+				)
 			}
+
+			r.HandleFunc(route.URI,
+				s.Middlewares.Instrument(route.URI, route.Method,
+					middlewares.Trace(route.URI, route.Method,
+						middlewares.RequestID(s.Middlewares.Logger(middlewares.LimitBody(maxBodyBytes, handler))),
+					),
+				),
+			).Methods(route.Method)
 		}
 	}
 	return r
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"api/src/authentication"
+	"api/src/controllers"
+	"api/src/middlewares"
+	"net/http"
+)
+
+func SessionRoutes(sessionController *controllers.SessionController) []Route {
+	return []Route{
+		{
+			URI:            "/users/{id}/sessions",
+			Method:         http.MethodGet,
+			Function:       sessionController.GetSessions,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+		{
+			URI:            "/users/{id}/sessions",
+			Method:         http.MethodDelete,
+			Function:       sessionController.RevokeOtherSessions,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+		{
+			URI:            "/users/{id}/sessions/{sessionId}",
+			Method:         http.MethodDelete,
+			Function:       sessionController.RevokeSession,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+	}
+}
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"api/src/authentication"
+	"api/src/controllers"
+	"api/src/middlewares"
+	"net/http"
+)
+
+func TokenRoutes(tokenController *controllers.TokenController) []Route {
+	return []Route{
+		{
+			URI:            "/users/{id}/tokens",
+			Method:         http.MethodPost,
+			Function:       tokenController.CreateToken,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+		{
+			URI:            "/users/{id}/tokens",
+			Method:         http.MethodGet,
+			Function:       tokenController.GetTokens,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+		{
+			URI:            "/users/{id}/tokens/{tokenId}",
+			Method:         http.MethodDelete,
+			Function:       tokenController.RevokeToken,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+	}
+}
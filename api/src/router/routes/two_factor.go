@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"api/src/authentication"
+	"api/src/controllers"
+	"api/src/middlewares"
+	"net/http"
+)
+
+func TwoFactorRoutes(twoFactorController *controllers.TwoFactorController) []Route {
+	return []Route{
+		{
+			URI:            "/users/{id}/2fa/setup",
+			Method:         http.MethodPost,
+			Function:       twoFactorController.Setup,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+		{
+			URI:            "/users/{id}/2fa/confirm",
+			Method:         http.MethodPost,
+			Function:       twoFactorController.Confirm,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+		{
+			URI:            "/users/{id}/2fa/disable",
+			Method:         http.MethodPost,
+			Function:       twoFactorController.Disable,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+	}
+}
@@ -1,7 +1,10 @@
 package routes
 
 import (
+	"api/src/authentication"
+	"api/src/config"
 	"api/src/controllers"
+	"api/src/middlewares"
 	"net/http"
 )
 
@@ -12,60 +15,89 @@ func UserRoutes(userController *controllers.UserController) []Route {
 			Method:         http.MethodPost,
 			Function:       userController.CreateUser,
 			Authentication: false,
+			RateLimit:      config.RateLimitSignup,
+		},
+		{
+			URI:            "/users/verify-email",
+			Method:         http.MethodPost,
+			Function:       userController.VerifyEmail,
+			Authentication: false,
 		},
 		{
 			URI:            "/users",
 			Method:         http.MethodGet,
 			Function:       userController.GetUsers,
 			Authentication: true,
+			Scope:          authentication.ScopeUsersRead,
 		},
 		{
 			URI:            "/users/{id}",
 			Method:         http.MethodGet,
 			Function:       userController.GetUser,
 			Authentication: true,
+			Scope:          authentication.ScopeUsersRead,
 		},
 		{
 			URI:            "/users/{id}",
 			Method:         http.MethodPut,
 			Function:       userController.UpdateUser,
 			Authentication: true,
+			Scope:          authentication.ScopeUsersWrite,
+			Permission:     authentication.PermissionManageUsers,
+			Owner:          middlewares.OwnedByPathUser,
 		},
 		{
 			URI:            "/users/{id}",
 			Method:         http.MethodDelete,
 			Function:       userController.DeleteUser,
 			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Permission:     authentication.PermissionManageUsers,
+			Owner:          middlewares.OwnedByPathUser,
+		},
+		{
+			URI:            "/users/{id}/role",
+			Method:         http.MethodPut,
+			Function:       userController.UpdateRole,
+			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Permission:     authentication.PermissionManageRoles,
 		},
 		{
 			URI:            "/users/{id}/follow",
 			Method:         http.MethodPost,
 			Function:       userController.FollowUser,
 			Authentication: true,
+			Scope:          authentication.ScopeFollowsWrite,
 		},
 		{
 			URI:            "/users/{id}/unfollow",
 			Method:         http.MethodPost,
 			Function:       userController.UnfollowUser,
 			Authentication: true,
+			Scope:          authentication.ScopeFollowsWrite,
 		},
 		{
 			URI:            "/users/{id}/followers",
 			Method:         http.MethodGet,
 			Function:       userController.GetFollowers,
 			Authentication: true,
+			Scope:          authentication.ScopeUsersRead,
 		},
 		{
 			URI:            "/users/{id}/following",
 			Method:         http.MethodGet,
 			Function:       userController.GetFollowing,
 			Authentication: true,
+			Scope:          authentication.ScopeUsersRead,
 		},
 		{
 			URI:            "/users/{id}/password",
 			Method:         http.MethodPost,
 			Function:       userController.UpdatePassword,
 			Authentication: true,
+			Scope:          authentication.ScopeAccount,
+			Owner:          middlewares.OwnedByPathUser,
 		},
 	}
 }
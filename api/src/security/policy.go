@@ -0,0 +1,164 @@
+package security
+
+import (
+	"api/src/apperrors"
+	"api/src/config"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const rangePrefixLength = 5
+
+type BreachedPasswordSource interface {
+	Range(prefix string) ([]string, error)
+}
+
+var breachedPasswords BreachedPasswordSource
+
+type fileBreachedPasswordSource struct {
+	path string
+}
+
+func loadBreachedPasswords(path string) error {
+	if path == "" {
+		breachedPasswords = nil
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	file.Close()
+
+	breachedPasswords = fileBreachedPasswordSource{path: path}
+	return nil
+}
+
+func CheckPasswordPolicy(password string) error {
+	if utf8.RuneCountInString(password) < config.PasswordMinLength {
+		return apperrors.Invalid(apperrors.Field("password", "too_short", "password.too_short", config.PasswordMinLength))
+	}
+
+	if breachedPasswords == nil {
+		return nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := breachedPasswords.Range(digest[:rangePrefixLength])
+	if err != nil {
+		return err
+	}
+
+	for _, suffix := range suffixes {
+		if suffix == digest[rangePrefixLength:] {
+			return apperrors.Invalid(apperrors.Field("password", "breached", "password.breached"))
+		}
+	}
+
+	return nil
+}
+
+func (f fileBreachedPasswordSource) Range(prefix string) ([]string, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	low, high := int64(0), info.Size()
+	for low < high {
+		middle := low + (high-low)/2
+
+		line, _, err := lineAt(file, middle)
+		if err != nil {
+			return nil, err
+		}
+
+		if line == "" || strings.ToUpper(line) >= prefix {
+			high = middle
+		} else {
+			low = middle + 1
+		}
+	}
+
+	var suffixes []string
+	for offset := low; ; {
+		line, next, err := lineAt(file, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		hash, _, _ := strings.Cut(strings.ToUpper(line), ":")
+		if !strings.HasPrefix(hash, prefix) {
+			return suffixes, nil
+		}
+
+		suffixes = append(suffixes, hash[len(prefix):])
+		offset = next
+	}
+}
+
+func lineAt(file *os.File, offset int64) (string, int64, error) {
+	if offset > 0 {
+		start, err := findNewline(file, offset-1)
+		if err != nil || start < 0 {
+			return "", 0, err
+		}
+		offset = start + 1
+	}
+
+	end, err := findNewline(file, offset)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if end < 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return "", 0, err
+		}
+		end = info.Size()
+	}
+
+	line := make([]byte, end-offset)
+	if _, err := file.ReadAt(line, offset); err != nil && err != io.EOF {
+		return "", 0, err
+	}
+
+	return strings.TrimSpace(string(line)), end + 1, nil
+}
+
+func findNewline(file *os.File, offset int64) (int64, error) {
+	buffer := make([]byte, 256)
+
+	for {
+		n, err := file.ReadAt(buffer, offset)
+		if index := bytes.IndexByte(buffer[:n], '\n'); index >= 0 {
+			return offset + int64(index), nil
+		}
+
+		if err == io.EOF {
+			return -1, nil
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		offset += int64(n)
+	}
+}
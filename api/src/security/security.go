@@ -1,11 +1,57 @@
 package security
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"api/src/config"
+	"errors"
+	"fmt"
+)
+
+var ErrPasswordMismatch = errors.New("password does not match")
+
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(encoded, password string) error
+	Recognizes(encoded string) bool
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	currentHasher Hasher = NewArgon2idHasher(64*1024, 3, 2)
+	knownHashers         = []Hasher{currentHasher, NewBcryptHasher(10)}
+)
+
+func Load() error {
+	argon2id := NewArgon2idHasher(uint32(config.Argon2Memory), uint32(config.Argon2Iterations), uint8(config.Argon2Parallelism))
+	bcryptHasher := NewBcryptHasher(config.BcryptCost)
+
+	switch config.PasswordHasher {
+	case "argon2id":
+		currentHasher = argon2id
+	case "bcrypt":
+		currentHasher = bcryptHasher
+	default:
+		return fmt.Errorf("unknown password hasher %q", config.PasswordHasher)
+	}
+	knownHashers = []Hasher{argon2id, bcryptHasher}
+
+	return loadBreachedPasswords(config.BreachedPasswords)
+}
 
 func Hash(password string) ([]byte, error) {
-	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	encoded, err := currentHasher.Hash(password)
+	return []byte(encoded), err
 }
 
 func ValidatePassword(hash string, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	for _, hasher := range knownHashers {
+		if hasher.Recognizes(hash) {
+			return hasher.Verify(hash, password)
+		}
+	}
+
+	return errors.New("unrecognized password hash format")
+}
+
+func NeedsRehash(hash string) bool {
+	return currentHasher.NeedsRehash(hash)
 }
@@ -0,0 +1,67 @@
+package security
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPMatchesRFC6238(t *testing.T) {
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range cases {
+		step, ok := ValidateTOTP(rfc6238Secret, tc.code, time.Unix(tc.unix, 0))
+		if !ok {
+			t.Errorf("code %s rejected at %d", tc.code, tc.unix)
+			continue
+		}
+
+		if step != tc.unix/totpPeriod {
+			t.Errorf("code %s matched step %d, want %d", tc.code, step, tc.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPAllowsOneStepOfSkew(t *testing.T) {
+	issued := time.Unix(1234567890, 0)
+
+	for _, offset := range []time.Duration{-totpPeriod * time.Second, 0, totpPeriod * time.Second} {
+		if _, ok := ValidateTOTP(rfc6238Secret, "005924", issued.Add(offset)); !ok {
+			t.Errorf("code rejected with %s of skew", offset)
+		}
+	}
+
+	for _, offset := range []time.Duration{-2 * totpPeriod * time.Second, 2 * totpPeriod * time.Second} {
+		if _, ok := ValidateTOTP(rfc6238Secret, "005924", issued.Add(offset)); ok {
+			t.Errorf("code accepted with %s of skew", offset)
+		}
+	}
+}
+
+func TestValidateTOTPRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	for _, code := range []string{"", "05924", "0059245", "abcdef"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+
+	if _, ok := ValidateTOTP("not base32!", "005924", now); ok {
+		t.Error("code accepted for an invalid secret")
+	}
+
+	if _, ok := ValidateTOTP(strings.ToLower(rfc6238Secret), " 005 924 ", now); !ok {
+		t.Error("code with spaces rejected for a lowercase secret")
+	}
+}
@@ -1,35 +1,105 @@
 package server
 
 import (
+	"api/src/authentication"
 	"api/src/config"
 	"api/src/database"
 	"api/src/migrations"
 	"api/src/router"
+	"api/src/security"
 	"api/src/server/services"
+	"api/src/tracing"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-func Start() error {
+func Start(logger *slog.Logger) error {
 	db, err := database.Connect()
 	if err != nil {
 		return fmt.Errorf("failed to connect to the database: %w", err)
 	}
 	defer db.Close()
 
-	err = migrations.RunMigrations()
-	if err != nil {
+	if err := migrations.RunMigrations(); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	s, err := services.Initialize(db)
+	migrationVersion, err := migrations.LatestVersion()
+	if err != nil {
+		return fmt.Errorf("failed to read the embedded migrations: %w", err)
+	}
+
+	if err := authentication.LoadKeys(); err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	if err := security.Load(); err != nil {
+		return fmt.Errorf("failed to configure password hashing: %w", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to configure tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("failed to flush traces", slog.Any("error", err))
+		}
+	}()
+
+	s, err := services.Initialize(db, migrationVersion, logger)
 	if err != nil {
 		return fmt.Errorf("failed to initialize services: %w", err)
 	}
 
-	r := router.NewRouter(s.AuthController, s.UserController, s.PublicationController)
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", config.Port),
+		Handler:           router.NewRouter(s),
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("listening", slog.Int("port", config.Port))
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	stop()
+	s.HealthController.Drain()
+	logger.Info("shutting down, waiting for load balancers to stop routing", slog.Duration("delay", config.ShutdownDrainDelay))
+	time.Sleep(config.ShutdownDrainDelay)
+
+	logger.Info("shutting down, draining connections", slog.Duration("timeout", config.ShutdownTimeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down gracefully: %w", err)
+	}
 
-	log.Printf("Listening on port %d\n", config.Port)
-	return http.ListenAndServe(fmt.Sprintf(":%d", config.Port), r)
+	return nil
 }
@@ -1,28 +1,107 @@
 package services
 
 import (
+	"api/src/config"
 	"api/src/controllers"
+	"api/src/mail"
+	"api/src/metrics"
+	"api/src/middlewares"
+	"api/src/oidc"
+	"api/src/ratelimit"
 	"api/src/repositories"
 	"database/sql"
+	"fmt"
+	"log/slog"
 )
 
 type Services struct {
+	Middlewares           *middlewares.Middlewares
 	AuthController        *controllers.AuthController
 	UserController        *controllers.UserController
 	PublicationController *controllers.PublicationController
+	TwoFactorController   *controllers.TwoFactorController
+	TokenController       *controllers.TokenController
+	SessionController     *controllers.SessionController
+	OIDCController        *controllers.OIDCController
+	OAuthController       *controllers.OAuthController
+	HealthController      *controllers.HealthController
+	Metrics               *metrics.Metrics
 }
 
-func Initialize(db *sql.DB) (*Services, error) {
-	userRepository := repositories.NewUserRepository(db)
-	publicationRepository := repositories.NewPublicationRepository(db)
+func Initialize(db *sql.DB, migrationVersion uint, logger *slog.Logger) (*Services, error) {
+	userRepository := repositories.NewUserRepository(db, logger)
+	publicationRepository := repositories.NewPublicationRepository(db, logger)
+	tokenRepository := repositories.NewTokenRepository(db, logger)
+	passwordResetRepository := repositories.NewPasswordResetRepository(db, logger)
+	emailVerificationRepository := repositories.NewEmailVerificationRepository(db, logger)
+	twoFactorRepository := repositories.NewTwoFactorRepository(db, logger)
+	loginAttemptRepository := repositories.NewLoginAttemptRepository(db, logger)
+	securityEventRepository := repositories.NewSecurityEventRepository(db, logger)
+	personalAccessTokenRepository := repositories.NewPersonalAccessTokenRepository(db, logger)
+	sessionRepository := repositories.NewSessionRepository(db, logger)
+	identityRepository := repositories.NewIdentityRepository(db, logger)
+	oauthRepository := repositories.NewOAuthRepository(db, logger)
+	magicLinkRepository := repositories.NewMagicLinkRepository(db, logger)
+	healthRepository := repositories.NewHealthRepository(db, logger)
 
-	userController := controllers.NewUserController(userRepository)
-	authContoller := controllers.NewAuthController(userRepository)
-	publicationController := controllers.NewPublicationController(publicationRepository)
+	collector := metrics.New(db)
+
+	rateLimitStore, err := newRateLimitStore(db, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	mailer, err := mail.NewSender()
+	if err != nil {
+		return nil, err
+	}
+
+	userController := controllers.NewUserController(userRepository, emailVerificationRepository, sessionRepository, mailer, collector, logger)
+	authContoller := controllers.NewAuthController(
+		userRepository,
+		tokenRepository,
+		passwordResetRepository,
+		twoFactorRepository,
+		loginAttemptRepository,
+		securityEventRepository,
+		sessionRepository,
+		magicLinkRepository,
+		mailer,
+		collector,
+		logger,
+	)
+	publicationController := controllers.NewPublicationController(publicationRepository, userRepository, collector)
+	twoFactorController := controllers.NewTwoFactorController(twoFactorRepository, userRepository, authContoller)
+	tokenController := controllers.NewTokenController(personalAccessTokenRepository)
+	sessionController := controllers.NewSessionController(sessionRepository)
+	oidcController := controllers.NewOIDCController(oidc.NewProviders(), identityRepository, userRepository, authContoller, logger)
+	oauthController := controllers.NewOAuthController(oauthRepository, tokenRepository, sessionRepository)
+	healthController := controllers.NewHealthController(healthRepository, migrationVersion, logger)
 
 	return &Services{
+		Middlewares:           middlewares.NewMiddlewares(userRepository, sessionRepository, personalAccessTokenRepository, publicationRepository, rateLimitStore, collector, logger),
 		AuthController:        authContoller,
 		UserController:        userController,
 		PublicationController: publicationController,
+		TwoFactorController:   twoFactorController,
+		TokenController:       tokenController,
+		SessionController:     sessionController,
+		OIDCController:        oidcController,
+		OAuthController:       oauthController,
+		HealthController:      healthController,
+		Metrics:               collector,
 	}, nil
 }
+
+func newRateLimitStore(db *sql.DB, logger *slog.Logger) (ratelimit.Store, error) {
+	switch config.RateLimitStore {
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "postgres":
+		return repositories.NewRateLimitRepository(db, logger), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", config.RateLimitStore)
+	}
+}